@@ -0,0 +1,242 @@
+// Package admin provides an http.Handler that exposes and controls the
+// breakers in a circuit.BreakerBox.
+//
+// Routes, relative to where the handler is mounted:
+//
+//	GET  /                      live HTML dashboard
+//	GET  /breakers              all breakers, with error counts and config
+//	GET  /breakers/{name}       one breaker, with error count, config, history and stats
+//	GET  /curve/{name}          the breaker's throttle probability curve
+//	GET  /events                Server-Sent Events stream of state changes
+//	POST /force-open/{name}     pin a breaker open
+//	POST /force-close/{name}    pin a breaker closed
+//	POST /reset/{name}          unpin a breaker, clear its errors and close it
+//
+// Breaker names may contain slashes. POST routes are disabled unless an
+// authorizer is configured with WithAuthorizer or WithBearerToken.
+// To serve the handler under a prefix, use http.StripPrefix:
+//
+//	mux.Handle("/admin/circuit/", http.StripPrefix("/admin/circuit", admin.NewHandler(box)))
+//
+// The dashboard is then at /admin/circuit/. It is embedded in the binary
+// and needs no external services.
+//
+// PublishExpvar publishes the same snapshots as an expvar.Var, for
+// services that already serve /debug/vars.
+package admin
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+//go:embed dashboard.html
+var dashboard []byte
+
+// DefaultHeartbeat is the default interval between keep-alive
+// comments on the event stream.
+const DefaultHeartbeat = 15 * time.Second
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithAuthorizer enables the POST routes, allowing a request only if fn returns true.
+func WithAuthorizer(fn func(*http.Request) bool) Option {
+	return func(h *Handler) {
+		h.authorize = fn
+	}
+}
+
+// WithBearerToken enables the POST routes for requests that carry
+// an "Authorization: Bearer <token>" header with the given token.
+// It panics if token is empty, since an empty token would admit any
+// request with a bare "Bearer " header; this catches, for example, an
+// unset environment variable at startup.
+func WithBearerToken(token string) Option {
+	if token == "" {
+		panic("admin: WithBearerToken requires a non-empty token")
+	}
+	return WithAuthorizer(func(r *http.Request) bool {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+	})
+}
+
+// WithHeartbeat sets the interval between keep-alive comments on the
+// event stream. The default is 15 seconds.
+func WithHeartbeat(d time.Duration) Option {
+	return func(h *Handler) {
+		h.heartbeat = d
+	}
+}
+
+// Handler serves the admin routes for a BreakerBox.
+type Handler struct {
+	box       *circuit.BreakerBox
+	authorize func(*http.Request) bool
+	heartbeat time.Duration
+	mux       *http.ServeMux
+}
+
+// NewHandler returns a Handler for the given box.
+func NewHandler(box *circuit.BreakerBox, opts ...Option) *Handler {
+	h := &Handler{
+		box:       box,
+		heartbeat: DefaultHeartbeat,
+		mux:       http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("GET /{$}", h.dashboard)
+	h.mux.HandleFunc("GET /breakers", h.list)
+	h.mux.HandleFunc("GET /breakers/{name...}", h.detail)
+	h.mux.HandleFunc("GET /curve/{name...}", h.curve)
+	h.mux.HandleFunc("GET /events", h.events)
+	h.mux.HandleFunc("POST /force-open/{name...}", h.action((*circuit.Breaker).ForceOpen))
+	h.mux.HandleFunc("POST /force-close/{name...}", h.action((*circuit.Breaker).ForceClose))
+	h.mux.HandleFunc("POST /reset/{name...}", h.action((*circuit.Breaker).Reset))
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) dashboard(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(dashboard)
+}
+
+func (h *Handler) list(w http.ResponseWriter, _ *http.Request) {
+	breakers := h.box.Breakers()
+	states := make([]circuit.BreakerState, 0, len(breakers))
+	for _, b := range breakers {
+		states = append(states, b.Snapshot(circuit.IncludeErrorCount(), circuit.IncludeConfig()))
+	}
+	writeJSON(w, http.StatusOK, states)
+}
+
+func (h *Handler) detail(w http.ResponseWriter, r *http.Request) {
+	b := h.lookup(w, r)
+	if b == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, b.Snapshot(circuit.IncludeErrorCount(), circuit.IncludeConfig(), circuit.IncludeHistory(), circuit.IncludeStats()))
+}
+
+func (h *Handler) curve(w http.ResponseWriter, r *http.Request) {
+	b := h.lookup(w, r)
+	if b == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Name  string   `json:"name"`
+		Curve []uint32 `json:"curve"`
+	}{b.Name(), b.ThrottleCurve()})
+}
+
+// action returns a handler that applies fn to the named breaker
+// and responds with the resulting state.
+func (h *Handler) action(fn func(*circuit.Breaker)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.authorize == nil {
+			writeError(w, http.StatusForbidden, "admin actions are disabled")
+			return
+		}
+		if !h.authorize(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		b := h.lookup(w, r)
+		if b == nil {
+			return
+		}
+		fn(b)
+		writeJSON(w, http.StatusOK, b.Snapshot(circuit.IncludeErrorCount()))
+	}
+}
+
+// events streams the current state of every breaker, followed by every
+// state change, as "state" events until the client disconnects.
+func (h *Handler) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	// subscribe before sending current states so no change is missed
+	changes, unsubscribe := h.box.Subscribe(64)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, b := range h.box.Breakers() {
+		if err := writeEvent(w, b.Snapshot(circuit.IncludeErrorCount())); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case state := <-changes:
+			if err := writeEvent(w, state); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// lookup returns the breaker named in the path, or responds with 404.
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request) *circuit.Breaker {
+	name := r.PathValue("name")
+	b := h.box.Load(name)
+	if b == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("breaker %q not found", name))
+	}
+	return b
+}
+
+func writeEvent(w http.ResponseWriter, state circuit.BreakerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: state\ndata: %s\n\n", data)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
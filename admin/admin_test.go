@@ -0,0 +1,325 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+func newBox(t *testing.T) *circuit.BreakerBox {
+	t.Helper()
+	box := circuit.NewBreakerBox()
+	for _, name := range []string{"users", "users/get", "orders"} {
+		if _, err := box.Create(circuit.WithName(name), circuit.WithThreshold(5), circuit.WithBackOff(30*time.Second)); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return box
+}
+
+func do(t *testing.T, h http.Handler, method, path, token string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var body map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec, body
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+		box := newBox(t)
+		box.Load("orders").Allow(context.Background())
+		h := NewHandler(box)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/breakers", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("expected JSON, got %s", ct)
+		}
+		var states []circuit.BreakerState
+		if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if len(states) != 3 || states[0].Name != "orders" || states[2].Name != "users/get" {
+			t.Fatalf("unexpected breakers: %+v", states)
+		}
+		if states[0].State != circuit.Closed || states[0].ErrorCount == nil || states[0].Config == nil {
+			t.Fatalf("expected closed state with error count and config, got %+v", states[0])
+		}
+	})
+
+	t.Run("detail", func(t *testing.T) {
+		t.Parallel()
+		box := newBox(t)
+		done, _ := box.Load("users/get").Allow(context.Background())
+		done(context.DeadlineExceeded)
+		h := NewHandler(box)
+
+		rec, body := do(t, h, http.MethodGet, "/breakers/users/get", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if body["name"] != "users/get" || body["state"] != "closed" || body["error_count"] != float64(1) {
+			t.Fatalf("unexpected detail: %v", body)
+		}
+		cfg, _ := body["config"].(map[string]any)
+		if cfg["backoff"] != "30s" || cfg["threshold"] != float64(5) {
+			t.Fatalf("unexpected config: %v", cfg)
+		}
+		if _, ok := body["stats"]; ok {
+			t.Fatalf("expected no stats unless enabled, got %v", body["stats"])
+		}
+	})
+
+	t.Run("detail with stats", func(t *testing.T) {
+		t.Parallel()
+		box := circuit.NewBreakerBox()
+		b, _ := box.Create(circuit.WithName("stats"), circuit.WithStats(time.Minute))
+		done, _ := b.Allow(context.Background())
+		done(nil)
+
+		_, body := do(t, NewHandler(box), http.MethodGet, "/breakers/stats", "")
+		stats, _ := body["stats"].(map[string]any)
+		if stats["successes"] != float64(1) || stats["window"] != "1m0s" {
+			t.Fatalf("unexpected stats: %v", body["stats"])
+		}
+	})
+
+	t.Run("curve", func(t *testing.T) {
+		t.Parallel()
+		box := circuit.NewBreakerBox()
+		box.Create(circuit.WithName("api"), circuit.WithEstimationFunc(circuit.Logarithmic))
+
+		rec := httptest.NewRecorder()
+		NewHandler(box).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/curve/api", nil))
+		var body struct {
+			Name  string   `json:"name"`
+			Curve []uint32 `json:"curve"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if body.Name != "api" || len(body.Curve) != 100 || body.Curve[0] != circuit.Logarithmic(1) || body.Curve[99] != circuit.Logarithmic(100) {
+			t.Fatalf("unexpected curve: %+v", body)
+		}
+
+		rec, _ = do(t, NewHandler(box), http.MethodGet, "/curve/nope", "")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("dashboard", func(t *testing.T) {
+		t.Parallel()
+		srv := httptest.NewServer(http.StripPrefix("/admin", NewHandler(newBox(t))))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/admin/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+			t.Fatalf("expected HTML, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		page, _ := io.ReadAll(resp.Body)
+		// the page must use relative URLs to work under a prefix
+		for _, want := range []string{"<title>circuit dashboard</title>", "new EventSource('events')", "fetch('breakers')", "'curve/'"} {
+			if !strings.Contains(string(page), want) {
+				t.Fatalf("expected dashboard to contain %q", want)
+			}
+		}
+
+		resp, err = http.Get(srv.URL + "/admin/nope")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 for unknown path, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("detail not found", func(t *testing.T) {
+		t.Parallel()
+		rec, body := do(t, NewHandler(newBox(t)), http.MethodGet, "/breakers/nope", "")
+		if rec.Code != http.StatusNotFound || !strings.Contains(body["error"].(string), "nope") {
+			t.Fatalf("expected 404, got %d %v", rec.Code, body)
+		}
+	})
+
+	t.Run("actions disabled without authorizer", func(t *testing.T) {
+		t.Parallel()
+		box := newBox(t)
+		rec, _ := do(t, NewHandler(box), http.MethodPost, "/force-open/users", "")
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", rec.Code)
+		}
+		if box.Load("users").State() != circuit.Closed {
+			t.Fatal("expected breaker to be unchanged")
+		}
+	})
+
+	t.Run("actions require token", func(t *testing.T) {
+		t.Parallel()
+		box := newBox(t)
+		h := NewHandler(box, WithBearerToken("s3cret"))
+		for _, token := range []string{"", "wrong"} {
+			rec, _ := do(t, h, http.MethodPost, "/force-open/users", token)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401 for token %q, got %d", token, rec.Code)
+			}
+			if rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Fatal("expected WWW-Authenticate header")
+			}
+		}
+		if box.Load("users").State() != circuit.Closed {
+			t.Fatal("expected breaker to be unchanged")
+		}
+	})
+
+	t.Run("empty token", func(t *testing.T) {
+		t.Parallel()
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected a panic for an empty token")
+			}
+		}()
+		WithBearerToken("")
+	})
+
+	t.Run("force open, force close and reset", func(t *testing.T) {
+		t.Parallel()
+		box := newBox(t)
+		h := NewHandler(box, WithBearerToken("s3cret"))
+		b := box.Load("users/get")
+
+		rec, body := do(t, h, http.MethodPost, "/force-open/users/get", "s3cret")
+		if rec.Code != http.StatusOK || body["state"] != "open" || body["forced"] != true {
+			t.Fatalf("unexpected force-open response: %d %v", rec.Code, body)
+		}
+		if _, err := b.Allow(context.Background()); err == nil {
+			t.Fatal("expected forced-open breaker to reject")
+		}
+
+		_, body = do(t, h, http.MethodPost, "/force-close/users/get", "s3cret")
+		if body["state"] != "closed" || body["forced"] != true {
+			t.Fatalf("unexpected force-close response: %v", body)
+		}
+
+		_, body = do(t, h, http.MethodPost, "/reset/users/get", "s3cret")
+		if body["state"] != "closed" || body["forced"] != nil {
+			t.Fatalf("unexpected reset response: %v", body)
+		}
+
+		_, body = do(t, h, http.MethodGet, "/breakers/users/get", "")
+		history, _ := body["history"].([]any)
+		if len(history) != 2 || history[0].(map[string]any)["reason"] != "forced open" {
+			t.Fatalf("expected detail to include history, got %v", body["history"])
+		}
+
+		rec, _ = do(t, h, http.MethodPost, "/reset/nope", "s3cret")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		t.Parallel()
+		rec, _ := do(t, NewHandler(newBox(t)), http.MethodGet, "/reset/users", "")
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("expected 405, got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandlerEvents(t *testing.T) {
+	t.Parallel()
+	box := newBox(t)
+	srv := httptest.NewServer(NewHandler(box, WithHeartbeat(10*time.Millisecond)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected event stream, got %s", ct)
+	}
+
+	events := make(chan circuit.BreakerState, 16)
+	heartbeats := make(chan struct{}, 16)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			line := sc.Text()
+			if strings.HasPrefix(line, ": heartbeat") {
+				select {
+				case heartbeats <- struct{}{}:
+				default:
+				}
+			}
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok {
+				continue
+			}
+			var state circuit.BreakerState
+			if json.Unmarshal([]byte(data), &state) == nil {
+				events <- state
+			}
+		}
+	}()
+
+	next := func() circuit.BreakerState {
+		t.Helper()
+		select {
+		case state := <-events:
+			return state
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+		return circuit.BreakerState{}
+	}
+
+	// current state of every breaker is sent first
+	for _, want := range []string{"orders", "users", "users/get"} {
+		if got := next(); got.Name != want || got.State != circuit.Closed {
+			t.Fatalf("expected initial closed state for %s, got %+v", want, got)
+		}
+	}
+
+	box.Load("users").ForceOpen()
+	if got := next(); got.Name != "users" || got.State != circuit.Open {
+		t.Fatalf("expected users to open, got %+v", got)
+	}
+
+	select {
+	case <-heartbeats:
+	case <-time.After(time.Second):
+		t.Fatal("expected heartbeat")
+	}
+}
@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"expvar"
+
+	"github.com/schigh/circuit"
+)
+
+// Expvar returns an expvar.Var whose value is a JSON object mapping each
+// breaker in box to its snapshot, with error count, config and, if
+// enabled with circuit.WithStats, rolling stats. The snapshots are taken
+// when the var is read, so breakers added to the box later are included.
+func Expvar(box *circuit.BreakerBox) expvar.Var {
+	return expvar.Func(func() any {
+		states := make(map[string]circuit.BreakerState)
+		for _, b := range box.Breakers() {
+			s := b.Snapshot(circuit.IncludeErrorCount(), circuit.IncludeConfig(), circuit.IncludeStats())
+			states[s.Name] = s
+		}
+		return states
+	})
+}
+
+// PublishExpvar publishes Expvar(box) under name, so the breakers appear
+// at /debug/vars. Like expvar.Publish, it panics if name is already in
+// use.
+func PublishExpvar(box *circuit.BreakerBox, name string) {
+	expvar.Publish(name, Expvar(box))
+}
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+func TestExpvar(t *testing.T) {
+	t.Parallel()
+
+	box := newBox(t)
+	v := Expvar(box)
+
+	read := func(t *testing.T, v expvar.Var) map[string]circuit.BreakerState {
+		t.Helper()
+		var states map[string]circuit.BreakerState
+		if err := json.Unmarshal([]byte(v.String()), &states); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return states
+	}
+
+	states := read(t, v)
+	if len(states) != 3 {
+		t.Fatalf("expected 3 breakers, got %v", states)
+	}
+	users := states["users/get"]
+	if users.State != circuit.Closed || users.ErrorCount == nil || *users.ErrorCount != 0 {
+		t.Fatalf("expected closed with no errors, got %+v", users)
+	}
+	if users.Config == nil || users.Config.Threshold != 5 {
+		t.Fatalf("expected config, got %+v", users.Config)
+	}
+	if users.Stats != nil {
+		t.Fatal("expected no stats unless enabled")
+	}
+
+	// values are read lazily, including breakers added later
+	done, _ := box.Load("users/get").Allow(context.Background())
+	done(context.DeadlineExceeded)
+	b, _ := box.Create(circuit.WithName("late"), circuit.WithStats(time.Minute))
+	done, _ = b.Allow(context.Background())
+	done(nil)
+
+	states = read(t, v)
+	if n := states["users/get"].ErrorCount; n == nil || *n != 1 {
+		t.Fatalf("expected 1 error, got %v", n)
+	}
+	if s := states["late"].Stats; s == nil || s.Successes != 1 {
+		t.Fatalf("expected stats for the late breaker, got %+v", s)
+	}
+
+	t.Run("publish", func(t *testing.T) {
+		t.Parallel()
+		// the expvar registry is global and outlives the test, so the
+		// name must be unique to each run
+		name := fmt.Sprintf("circuit_test_%d", time.Now().UnixNano())
+		PublishExpvar(box, name)
+		published := expvar.Get(name)
+		if published == nil {
+			t.Fatal("expected the var to be published")
+		}
+		if states := read(t, published); len(states) != 4 {
+			t.Fatalf("expected 4 breakers, got %v", states)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected a panic when the name is reused")
+			}
+		}()
+		PublishExpvar(box, name)
+	})
+}
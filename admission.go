@@ -0,0 +1,24 @@
+package circuit
+
+// creditEpsilon absorbs rounding when fractions such as 0.01 are
+// accrued, so that a whole credit is not missed by a hair.
+const creditEpsilon = 1e-9
+
+// admits decides whether a throttled request is admitted, given the
+// fraction of requests to admit. By default the decision is random.
+// With deterministic throttling, each request accrues the fraction as
+// credit and is admitted once a whole credit has accrued, so admitted
+// requests are evenly spaced: at 0.25, every fourth request is admitted.
+// Must be called with stateMX held.
+func (b *Breaker) admits(pass float64) bool {
+	if !b.evenThrottle {
+		return b.float64() < pass
+	}
+
+	b.credit += max(pass, 0)
+	if b.credit < 1-creditEpsilon {
+		return false
+	}
+	b.credit = max(b.credit-1, 0)
+	return true
+}
@@ -0,0 +1,121 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeterministicThrottle(t *testing.T) {
+	t.Parallel()
+
+	// admitted returns the indexes of the requests admitted out of n.
+	admitted := func(b *Breaker, n int) []int {
+		var got []int
+		for i := 0; i < n; i++ {
+			if b.checkState() == nil {
+				got = append(got, i)
+			}
+		}
+		return got
+	}
+
+	// constant blocks the given percentage of requests at every tick.
+	constant := func(chance uint32) EstimationFunc {
+		return func(int) uint32 { return chance }
+	}
+
+	t.Run("admits evenly spaced requests", func(t *testing.T) {
+		t.Parallel()
+		for chance, every := range map[uint32]int{0: 1, 50: 2, 75: 4, 90: 10, 99: 100} {
+			b := mustNewBreaker(t,
+				WithBackOff(time.Hour),
+				WithEstimationFunc(constant(chance)),
+				WithDeterministicThrottle(true),
+			)
+			cycle(b, Open)
+			cycle(b, Throttled)
+
+			got := admitted(b, 200)
+			if len(got) != 200/every {
+				t.Fatalf("chance %d: expected %d admitted, got %v", chance, 200/every, got)
+			}
+			for i, idx := range got {
+				if want := (i+1)*every - 1; idx != want {
+					t.Fatalf("chance %d: expected request %d admitted, got %v", chance, want, got)
+				}
+			}
+		}
+	})
+
+	t.Run("blocks everything at 100", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithEstimationFunc(constant(100)), WithDeterministicThrottle(true))
+		cycle(b, Open)
+		cycle(b, Throttled)
+		if got := admitted(b, 500); len(got) != 0 {
+			t.Fatalf("expected no requests admitted, got %v", got)
+		}
+	})
+
+	t.Run("fine probabilities", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t,
+			WithBackOff(time.Hour),
+			WithProbabilityFunc(func(float64) float64 { return 0.995 }),
+			WithDeterministicThrottle(true),
+		)
+		cycle(b, Open)
+		cycle(b, Throttled)
+		if got := admitted(b, 400); len(got) != 2 || got[0] != 199 || got[1] != 399 {
+			t.Fatalf("expected every 200th request admitted, got %v", got)
+		}
+	})
+
+	t.Run("ramp-up", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(10), WithRampUp(25), WithDeterministicThrottle(true))
+		for i := 0; i <= 10; i++ {
+			b.tracker.incr()
+		}
+		b.State()
+		cycle(b, Throttled)
+		if got := admitted(b, 12); len(got) != 3 || got[0] != 3 || got[1] != 7 || got[2] != 11 {
+			t.Fatalf("expected every fourth request admitted, got %v", got)
+		}
+	})
+
+	t.Run("credit resets when throttled again", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithEstimationFunc(constant(50)), WithDeterministicThrottle(true))
+		cycle(b, Open)
+		cycle(b, Throttled)
+		admitted(b, 1) // half a credit
+		cycle(b, Open)
+		cycle(b, Throttled)
+		if got := admitted(b, 2); len(got) != 1 || got[0] != 1 {
+			t.Fatalf("expected the second request admitted, got %v", got)
+		}
+	})
+
+	t.Run("reconfigure and config", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t)
+		if b.Config().DeterministicThrottle {
+			t.Fatal("expected random throttling by default")
+		}
+		if err := b.Reconfigure(WithDeterministicThrottle(true)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c := b.Config()
+		if !c.DeterministicThrottle {
+			t.Fatal("expected deterministic throttling")
+		}
+		opts, err := c.Options()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !mustNewBreaker(t, opts...).evenThrottle {
+			t.Fatal("expected the config to round trip")
+		}
+	})
+}
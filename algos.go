@@ -78,7 +78,9 @@ func EaseInOut(tick int) uint32 {
 }
 
 // JitteredLinear is like Linear but adds random jitter of +/- 5
-// to reduce thundering herd effects during recovery.
+// to reduce thundering herd effects during recovery. The jitter is
+// drawn from the global source in math/rand/v2, even for a breaker
+// with WithRand; for reproducible jitter, use Jitter(Linear, 5, src).
 func JitteredLinear(tick int) uint32 {
 	base := 100 - tick
 	jitter := int(rand.IntN(11)) - 5 // [-5, 5]
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand/v2"
 	"path"
 	"runtime"
@@ -23,13 +24,16 @@ const (
 type Breaker struct {
 	// switches
 	openingResets bool // If true, the circuit breaker resets its error count upon opening
+	strict        bool // If true, invalid options are rejected rather than clamped
 
 	// state
 	threshold uint32 // Maximum number of errors allowed to occur in window
 	state     uint32 // Current state
+	forced    uint32 // 1 if the current state was pinned by ForceOpen or ForceClose
 
 	// event timestamps
 	lockedSince    int64 // Unix nano timestamp of current lock creation
+	lockedFor      int64 // Duration of the current lock, fixed when it is created
 	openSince      int64 // Unix nano timestamp of current open state creation
 	throttledSince int64 // Unix nano timestamp of current throttled state creation
 	closedSince    int64 // Unix nano timestamp of last closed time (or creation)
@@ -43,18 +47,63 @@ type Breaker struct {
 	lockout time.Duration // Length of time a breaker is locked out once it opens
 	window  time.Duration // Window of time to look for errors (e.g. 5 errors in 10 mins)
 
+	// lockout escalation
+	lockoutPolicy *LockoutPolicy // Optional policy for escalating the lockout
+	reopens       uint           // Consecutive Throttled -> Open transitions since the last close
+
+	// ramp-up recovery
+	rampStep uint32 // Admission gained per success while throttled; 0 uses the backoff instead
+	admit    uint32 // Percentage of requests admitted while ramping up, protected by stateMX
+
+	// deterministic throttling
+	evenThrottle bool    // If true, throttled requests are admitted by credit rather than at random
+	credit       float64 // Admission credit accrued while throttled, protected by stateMX
+
 	// misc
-	stateMX  sync.Mutex       // Protects state transitions in evaluateState/changeStateTo
-	tracker  *errTracker      // Error tracker
-	estimate EstimationFunc   // Function used to estimate throttling chance
-	metrics  MetricsCollector // Optional metrics collector
+	stateMX        sync.Mutex       // Protects state transitions in evaluateState/changeStateTo
+	tracker        *errTracker      // Error tracker
+	estimate       EstimationFunc   // Function used to estimate throttling chance
+	probability    ProbabilityFunc  // Higher-resolution alternative to estimate; takes precedence if set
+	estimationName string           // Name estimate or probability was chosen by; empty if unnamed
+	metrics        MetricsCollector // Optional metrics collector
+	labelsFunc     LabelFunc        // Optional extractor of call labels for a LabeledMetricsCollector
+	rng            *rand.Rand       // Optional random source for throttling and jitter, used with stateMX held
+	clock          Clock            // Optional clock; nil for the system clock
 
 	// orchestration
-	stateChange    chan BreakerState
-	boxStateChange chan BreakerState // set by BreakerBox.Create for forwarding
+	parent      *Breaker // Optional parent; outcomes propagate up, rejections propagate down
+	stateChange chan BreakerState
+	box         *BreakerBox // set by BreakerBox.Create for forwarding
+
+	// distributed state
+	store        StateStore    // Optional store shared with other replicas
+	peerID       string        // Identifies this replica in the store
+	mergePolicy  MergePolicy   // Decides whether peer states force this breaker open
+	syncInterval time.Duration // Minimum time between store exchanges
+	syncTimeout  time.Duration // Limit on each store exchange
+	lastSync     int64         // Unix nano timestamp of the last store exchange
+	syncing      uint32        // 1 while a store exchange is in flight
+	peersOpen    uint32        // 1 if the merged peer verdict is open
+
+	// diagnostics
+	historySize int      // Number of transitions kept
+	history     *history // Recent transitions, protected by stateMX
+
+	// rolling stats
+	statsWindow time.Duration                // Window of the stats, protected by stateMX; 0 disables them
+	stats       atomic.Pointer[rollingStats] // Stats, if enabled; replaced when the window is reconfigured
+
+	// flap detection
+	flapTransitions int           // Transitions allowed within flapPeriod; 0 disables detection
+	flapPeriod      time.Duration // Period over which transitions are counted
+	flapStable      time.Duration // Time closed after which flapping ends
+	dampenMax       time.Duration // Maximum dampened lockout; 0 disables dampening
+	flap            flapState
 
 	// hooks
-	onStateChange func(breakerName string, from, to State)
+	onStateChange  func(breakerName string, from, to State)
+	onConfigChange func(breakerName string)
+	onFlap         func(breakerName string, transitions int)
 
 	// error classification
 	isSuccessful func(error) bool
@@ -63,6 +112,8 @@ type Breaker struct {
 
 // NewBreaker creates a new Breaker using functional options.
 // All new Breaker instances MUST be created with this function.
+// If any option is invalid, the returned error describes every
+// problem found; each is an OptionError.
 func NewBreaker(opts ...Option) (*Breaker, error) {
 	b := &Breaker{}
 
@@ -70,6 +121,10 @@ func NewBreaker(opts ...Option) (*Breaker, error) {
 		opt(b)
 	}
 
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+
 	// if there is no name, just make a signature from the caller
 	if b.name == "" {
 		function, file, line, _ := runtime.Caller(1)
@@ -84,6 +139,50 @@ func NewBreaker(opts ...Option) (*Breaker, error) {
 			".", "_",
 		)
 	}
+	b.applyDefaults()
+
+	if b.store != nil {
+		if b.peerID == "" {
+			b.peerID = defaultPeerID()
+		}
+		if b.mergePolicy == nil {
+			b.mergePolicy = AnyOpen
+		}
+		if b.syncInterval == 0 {
+			b.syncInterval = DefaultSyncInterval
+		}
+		if b.syncTimeout == 0 {
+			b.syncTimeout = DefaultSyncTimeout
+		}
+	}
+
+	if b.historySize == 0 {
+		b.historySize = DefaultHistorySize
+	}
+	if b.flapStable == 0 {
+		b.flapStable = b.flapPeriod
+	}
+	b.history = newHistory(b.historySize)
+	if b.statsWindow > 0 {
+		b.stats.Store(newRollingStats(b.statsWindow))
+	}
+
+	b.stateChange = make(chan BreakerState, 16)
+	b.tracker = newErrTracker(b.window, b.clock)
+	now := b.now()
+	b.closedSince = now.UnixNano()
+
+	b.stateChange <- BreakerState{
+		Name:        b.name,
+		State:       Closed,
+		ClosedSince: &now,
+	}
+
+	return b, nil
+}
+
+// applyDefaults fills in unset options and clamps values to their minimums.
+func (b *Breaker) applyDefaults() {
 	if b.timeout == 0 {
 		b.timeout = DefaultTimeout
 	}
@@ -99,22 +198,10 @@ func NewBreaker(opts ...Option) (*Breaker, error) {
 	if b.window < minimumWindow {
 		b.window = minimumWindow
 	}
-	if b.estimate == nil {
+	if b.estimate == nil && b.probability == nil {
 		b.estimate = Linear
+		b.estimationName = "linear"
 	}
-
-	b.stateChange = make(chan BreakerState, 16)
-	b.tracker = newErrTracker(b.window)
-	now := time.Now()
-	b.closedSince = now.UnixNano()
-
-	b.stateChange <- BreakerState{
-		Name:        b.name,
-		State:       Closed,
-		ClosedSince: &now,
-	}
-
-	return b, nil
 }
 
 // get the lock status
@@ -141,15 +228,22 @@ func (b *Breaker) setOpen(is bool) {
 		return
 	}
 
-	nowNano := time.Now().UnixNano()
+	nowNano := b.now().UnixNano()
 	atomic.SwapInt64(&b.openSince, nowNano)
 
-	if b.lockout == 0 {
+	lockout := b.nextLockout()
+	if lockout == 0 {
 		return
 	}
+	atomic.StoreInt64(&b.lockedFor, int64(lockout))
 	atomic.SwapInt64(&b.lockedSince, nowNano)
 }
 
+// lockedDuration returns the length of the current lock.
+func (b *Breaker) lockedDuration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&b.lockedFor))
+}
+
 // get the throttle status
 func (b *Breaker) throttledStatus() (time.Time, bool) {
 	l := atomic.LoadInt64(&b.throttledSince)
@@ -165,7 +259,7 @@ func (b *Breaker) setThrottled(is bool) {
 		atomic.SwapInt64(&b.throttledSince, 0)
 		return
 	}
-	atomic.SwapInt64(&b.throttledSince, time.Now().UnixNano())
+	atomic.SwapInt64(&b.throttledSince, b.now().UnixNano())
 }
 
 // get the closed status
@@ -179,7 +273,7 @@ func (b *Breaker) closedStatus() (time.Time, bool) {
 
 func (b *Breaker) setClosed(is bool) {
 	if is {
-		atomic.SwapInt64(&b.closedSince, time.Now().UnixNano())
+		atomic.SwapInt64(&b.closedSince, b.now().UnixNano())
 		return
 	}
 	atomic.SwapInt64(&b.closedSince, 0)
@@ -191,15 +285,28 @@ func (b *Breaker) setClosed(is bool) {
 // to avoid blocking requests on user code.
 // Returns the BreakerState and whether a transition occurred,
 // so the caller can invoke hooks after releasing the lock.
-func (b *Breaker) changeStateTo(to uint32) (BreakerState, bool) {
+func (b *Breaker) changeStateTo(to uint32, reason string) (BreakerState, bool) {
 	from := atomic.SwapUint32(&b.state, to)
 	if from == to {
 		return BreakerState{}, false
 	}
 
+	// count errors before opening can reset them
+	t := Transition{
+		From:   State(from),
+		To:     State(to),
+		At:     b.now(),
+		Errors: int(b.tracker.size()),
+		Reason: reason,
+	}
+
+	// publish the transition to peers on the next request
+	atomic.StoreInt64(&b.lastSync, 0)
+
 	newState := BreakerState{
-		Name:  b.name,
-		State: State(to),
+		Name:     b.name,
+		State:    State(to),
+		Flapping: b.flap.flapping,
 	}
 
 	switch from {
@@ -219,27 +326,42 @@ func (b *Breaker) changeStateTo(to uint32) (BreakerState, bool) {
 			newState.Opened = &openedAt
 		}
 		if isLocked {
-			end := lockedAt.Add(b.lockout)
+			end := lockedAt.Add(b.lockedDuration())
 			newState.LockoutEnds = &end
 		}
 	case internalThrottled:
 		b.setThrottled(true)
+		b.admit = b.rampStep
+		b.credit = 0
 		ts, throttled := b.throttledStatus()
 		if throttled {
 			newState.Throttled = &ts
-			end := ts.Add(b.backoff)
-			newState.BackOffEnds = &end
+			b.throttleDetail(&newState, ts)
 		}
 	case internalClosed:
 		b.setClosed(true)
+		b.reopens = 0
 		ts, closed := b.closedStatus()
 		if closed {
 			newState.ClosedSince = &ts
 		}
 	}
 
+	b.emitStateChange(t, newState)
+
+	return newState, true
+}
+
+// emitStateChange records a transition in the history and with the
+// metrics collector, and sends the new state to the breaker's and box's
+// channels without blocking. Must be called with stateMX held.
+func (b *Breaker) emitStateChange(t Transition, newState BreakerState) {
+	if b.history != nil {
+		b.history.add(t)
+	}
+
 	if b.metrics != nil {
-		b.metrics.RecordStateChange(b.name, State(from), State(to))
+		b.metrics.RecordStateChange(b.name, t.From, t.To)
 	}
 
 	select {
@@ -247,14 +369,9 @@ func (b *Breaker) changeStateTo(to uint32) (BreakerState, bool) {
 	default:
 	}
 
-	if b.boxStateChange != nil {
-		select {
-		case b.boxStateChange <- newState:
-		default:
-		}
+	if b.box != nil {
+		b.box.forward(newState)
 	}
-
-	return newState, true
 }
 
 // evaluateState lazily evaluates and transitions state.
@@ -262,19 +379,26 @@ func (b *Breaker) changeStateTo(to uint32) (BreakerState, bool) {
 // Returns the pending onStateChange callback info (if any)
 // so the caller can invoke it after releasing the lock.
 func (b *Breaker) evaluateState() (from, to State, transitioned bool) {
+	if atomic.LoadUint32(&b.forced) == 1 {
+		return
+	}
+	b.settleFlap()
+
 	state := atomic.LoadUint32(&b.state)
 	var target uint32
+	var reason string
 	switch state {
 	case internalClosed:
 		if b.tracker.size() > b.threshold {
 			target = internalOpen
+			reason = reasonThresholdExceeded
 		} else {
 			return
 		}
 	case internalOpen:
 		lockedAt := atomic.LoadInt64(&b.lockedSince)
 		if lockedAt != 0 {
-			if time.Since(timeFromNS(lockedAt)) >= b.lockout {
+			if b.since(timeFromNS(lockedAt)) >= b.lockedDuration() {
 				atomic.StoreInt64(&b.lockedSince, 0)
 			} else {
 				return // still locked
@@ -282,87 +406,136 @@ func (b *Breaker) evaluateState() (from, to State, transitioned bool) {
 		}
 		if b.tracker.size() <= b.threshold {
 			target = internalThrottled
+			reason = reasonRecovering
 		} else {
 			return
 		}
 	case internalThrottled:
 		if b.tracker.size() > b.threshold {
 			target = internalOpen
-		} else {
+			reason = reasonThresholdExceeded
+			b.reopens++
+		} else if b.rampStep == 0 {
 			ts := atomic.LoadInt64(&b.throttledSince)
-			if ts != 0 && time.Since(timeFromNS(ts)) >= b.backoff {
+			if ts != 0 && b.since(timeFromNS(ts)) >= b.backoff {
 				target = internalClosed
+				reason = reasonBackOffElapsed
 			} else {
 				return
 			}
+		} else {
+			return // closed by rampUp
 		}
 	default:
 		return
 	}
 
-	_, changed := b.changeStateTo(target)
+	b.detectFlap(target)
+	_, changed := b.changeStateTo(target, reason)
 	if changed {
 		return State(state), State(target), true
 	}
 	return
 }
 
-// applyThrottle calculates throttle chance lazily from elapsed time.
+// notify invokes the hooks for a transition made by evaluateState.
+// Must be called without stateMX held.
+func (b *Breaker) notify(from, to State, transitioned bool) {
+	if !transitioned {
+		return
+	}
+	if b.onStateChange != nil {
+		b.onStateChange(b.name, from, to)
+	}
+	b.notifyFlap()
+}
+
+// applyThrottle calculates throttle chance lazily from elapsed time,
+// or from the current admission in ramp-up mode. An estimation function
+// sees the elapsed time as a tick; a probability function sees it exactly.
 func (b *Breaker) applyThrottle() error {
 	ts := atomic.LoadInt64(&b.throttledSince)
 	if ts == 0 {
 		return nil
 	}
-	elapsed := time.Since(timeFromNS(ts))
-	tick := int(elapsed * 100 / b.backoff)
-	if tick < 1 {
-		tick = 1
-	}
-	if tick > 100 {
-		tick = 100
+
+	var pass float64 // fraction of requests to admit
+	elapsed := b.since(timeFromNS(ts))
+	switch {
+	case b.rampStep > 0:
+		pass = float64(b.admit) / 100
+	case b.probability != nil:
+		pass = 1 - b.probability(float64(elapsed)/float64(b.backoff))
+	default:
+		tick := int(elapsed * 100 / b.backoff)
+		if tick < 1 {
+			tick = 1
+		}
+		if tick > 100 {
+			tick = 100
+		}
+		pass = (100 - float64(b.estimate(tick))) / 100
 	}
-	chance := b.estimate(tick)
-	if rand.Uint32N(100) >= chance {
+
+	if b.admits(pass) {
 		return nil
 	}
 	return ErrStateThrottled.withContext(b.name, Throttled)
 }
 
 // checkFitness determines if a request is allowed to proceed.
+// Ancestors are checked first, so an open parent short-circuits
+// its children and the returned error names the rejecting breaker.
+// If a StateStore is configured, a request allowed by the local state
+// is still rejected while the merged peer verdict is open.
 func (b *Breaker) checkFitness(ctx context.Context) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
 
+	// a breaker pinned closed allows every request, even when its
+	// parent or its peers would reject it
+	if atomic.LoadUint32(&b.forced) == 1 && atomic.LoadUint32(&b.state) == internalClosed {
+		return nil
+	}
+
+	if b.parent != nil {
+		if err := b.parent.checkFitness(ctx); err != nil {
+			b.recordRejected(ctx, err)
+			return err
+		}
+	}
+
+	if b.store != nil {
+		b.syncStore(ctx)
+	}
+	err := b.checkState()
+	if err == nil && b.store != nil && atomic.LoadUint32(&b.peersOpen) == 1 {
+		err = ErrStateOpen.withContext(b.name, Open)
+	}
+	if err != nil {
+		b.recordRejected(ctx, err)
+	}
+	return err
+}
+
+// checkState determines if the local state allows a request to proceed.
+func (b *Breaker) checkState() error {
 	// Fast path: if closed and no errors exceed threshold, skip the lock entirely.
 	// This is the overwhelmingly common case in production.
 	state := atomic.LoadUint32(&b.state)
-	if state == internalClosed && b.tracker.size() <= b.threshold {
+	if state == internalClosed && b.tracker.size() <= atomic.LoadUint32(&b.threshold) {
 		return nil
 	}
 
-	// Slow path: state may need to transition. Acquire lock.
-	b.stateMX.Lock()
-	from, to, transitioned := b.evaluateState()
-	b.stateMX.Unlock()
-
-	if transitioned && b.onStateChange != nil {
-		b.onStateChange(b.name, from, to)
-	}
+	// Slow path: state may need to transition.
+	state, throttleErr := b.evaluateAndThrottle()
 
-	state = atomic.LoadUint32(&b.state)
 	switch state {
 	case internalOpen:
-		if b.metrics != nil {
-			b.metrics.RecordRejected(b.name, Open)
-		}
 		return ErrStateOpen.withContext(b.name, Open)
 	case internalThrottled:
-		err := b.applyThrottle()
-		if err != nil && b.metrics != nil {
-			b.metrics.RecordRejected(b.name, Throttled)
-		}
-		return err
+		return throttleErr
 	case internalClosed:
 		return nil
 	default:
@@ -370,42 +543,79 @@ func (b *Breaker) checkFitness(ctx context.Context) error {
 	}
 }
 
-// recordOutcome classifies and records the result of a call.
-func (b *Breaker) recordOutcome(err error, elapsed time.Duration) {
-	if err == nil {
-		if b.metrics != nil {
-			b.metrics.RecordSuccess(b.name, elapsed)
+// evaluateAndThrottle evaluates the state and, if throttled, decides
+// whether to admit the request. The throttle decision is made under the
+// lock, since backoff and estimation settings may be reconfigured. The
+// estimation or probability function may panic at a tick it was not
+// validated at; the lock is then released and the hooks for any
+// transition invoked before the panic continues, so the breaker stays usable.
+func (b *Breaker) evaluateAndThrottle() (state uint32, throttleErr error) {
+	var from, to State
+	var transitioned bool
+	b.stateMX.Lock()
+	defer func() {
+		if r := recover(); r != nil {
+			b.stateMX.Unlock()
+			b.notify(from, to, transitioned)
+			panic(r)
 		}
+	}()
+
+	from, to, transitioned = b.evaluateState()
+	state = atomic.LoadUint32(&b.state)
+	if state == internalThrottled {
+		throttleErr = b.applyThrottle()
+	}
+	b.stateMX.Unlock()
+
+	b.notify(from, to, transitioned)
+	return state, throttleErr
+}
+
+// recordOutcome classifies and records the result of a call made with ctx.
+func (b *Breaker) recordOutcome(ctx context.Context, err error, elapsed time.Duration) {
+	if err == nil {
+		b.recordSucceeded(ctx, elapsed)
 		return
 	}
 
 	// excluded errors are not tracked at all
 	if b.isExcluded != nil && b.isExcluded(err) {
-		if b.metrics != nil {
-			b.metrics.RecordExcluded(b.name, err)
-		}
+		b.recordExcluded(ctx, err)
 		return
 	}
 
 	// errors classified as successful don't count as failures
 	if b.isSuccessful != nil && b.isSuccessful(err) {
-		if b.metrics != nil {
-			b.metrics.RecordSuccess(b.name, elapsed)
-		}
+		b.recordSucceeded(ctx, elapsed)
 		return
 	}
 
 	// classify deadline exceeded as timeout
 	if errors.Is(err, context.DeadlineExceeded) {
-		if b.metrics != nil {
-			b.metrics.RecordTimeout(b.name)
-		}
+		b.recordTimeout(ctx)
 	}
 
 	// failure
-	b.tracker.incr()
-	if b.metrics != nil {
-		b.metrics.RecordError(b.name, elapsed, err)
+	b.recordFailure(ctx, err, elapsed)
+}
+
+// recordSucceeded records a success against this breaker and all of
+// its ancestors, and advances their ramp-up.
+func (b *Breaker) recordSucceeded(ctx context.Context, elapsed time.Duration) {
+	for p := b; p != nil; p = p.parent {
+		p.recordSuccess(ctx, elapsed)
+		p.rampUp(true)
+	}
+}
+
+// recordFailure tracks a failure against this breaker and all of its
+// ancestors, and sets back their ramp-up.
+func (b *Breaker) recordFailure(ctx context.Context, err error, elapsed time.Duration) {
+	for p := b; p != nil; p = p.parent {
+		p.tracker.incr()
+		p.recordError(ctx, elapsed, err)
+		p.rampUp(false)
 	}
 }
 
@@ -420,9 +630,9 @@ func (b *Breaker) Allow(ctx context.Context) (func(error), error) {
 	if err := b.checkFitness(ctx); err != nil {
 		return nil, err
 	}
-	start := time.Now()
+	start := b.now()
 	done := func(err error) {
-		b.recordOutcome(err, time.Since(start))
+		b.recordOutcome(ctx, err, b.since(start))
 	}
 	return done, nil
 }
@@ -432,6 +642,11 @@ func (b *Breaker) Name() string {
 	return b.name
 }
 
+// Parent returns the breaker's parent, or nil if it has none.
+func (b *Breaker) Parent() *Breaker {
+	return b.parent
+}
+
 // State returns the current state of the circuit breaker.
 // This triggers lazy state evaluation.
 func (b *Breaker) State() State {
@@ -439,9 +654,7 @@ func (b *Breaker) State() State {
 	from, to, transitioned := b.evaluateState()
 	b.stateMX.Unlock()
 
-	if transitioned && b.onStateChange != nil {
-		b.onStateChange(b.name, from, to)
-	}
+	b.notify(from, to, transitioned)
 
 	return State(atomic.LoadUint32(&b.state))
 }
@@ -456,22 +669,148 @@ func (b *Breaker) Size() int {
 	return int(b.tracker.size())
 }
 
+// Config returns the breaker's effective configuration, after defaults
+// and clamping have been applied. The estimation function is reported by
+// the name a configuration document selected it by or, if set with an
+// option, by its built-in name; any other function is CustomEstimation.
+// The returned value is a copy and can be passed to ApplyConfig, unless
+// its Estimation is CustomEstimation, which Validate rejects as unknown.
+// To round trip a custom function, register it and select it by name.
+func (b *Breaker) Config() BreakerConfig {
+	b.stateMX.Lock()
+	defer b.stateMX.Unlock()
+	return b.config()
+}
+
+// config builds the effective configuration.
+// Must be called with stateMX held.
+func (b *Breaker) config() BreakerConfig {
+	c := BreakerConfig{
+		Name:                  b.name,
+		Timeout:               Duration(b.timeout),
+		BackOff:               Duration(b.backoff),
+		Window:                Duration(b.window),
+		Threshold:             b.threshold,
+		LockOut:               Duration(b.lockout),
+		OpeningResetsErrors:   b.openingResets,
+		DeterministicThrottle: b.evenThrottle,
+		Strict:                b.strict,
+		StatsWindow:           Duration(b.statsWindow),
+	}
+	c.Estimation = b.estimationName
+	if c.Estimation == "" {
+		c.Estimation = CustomEstimation
+	}
+	c.Smooth = b.probability != nil
+	if b.parent != nil {
+		c.Parent = b.parent.name
+	}
+	return c
+}
+
+// ThrottleCurve returns the probability, in percent, that a throttled
+// request is rejected at each tick of the backoff period. Index 0 is
+// tick 1, at the start of the backoff, and index 99 is tick 100, at its end.
+// A probability function is sampled at each tick and rounded.
+// In ramp-up mode the curve is not used; see WithRampUp.
+func (b *Breaker) ThrottleCurve() []uint32 {
+	b.stateMX.Lock()
+	estimate, probability := b.estimate, b.probability
+	b.stateMX.Unlock()
+
+	curve := make([]uint32, 100)
+	for i := range curve {
+		if probability != nil {
+			curve[i] = uint32(math.Round(100 * clampProgress(probability(float64(i+1)/100))))
+			continue
+		}
+		curve[i] = estimate(i + 1)
+	}
+	return curve
+}
+
+// SnapshotOption adds optional detail to a Snapshot.
+type SnapshotOption func(*snapshotOptions)
+
+type snapshotOptions struct {
+	config     bool
+	errorCount bool
+	history    bool
+	stats      bool
+}
+
+// IncludeConfig adds the breaker's effective configuration to a Snapshot.
+func IncludeConfig() SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.config = true
+	}
+}
+
+// IncludeErrorCount adds the number of errors in the current window to a Snapshot.
+func IncludeErrorCount() SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.errorCount = true
+	}
+}
+
+// IncludeHistory adds the breaker's recent transitions to a Snapshot.
+func IncludeHistory() SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.history = true
+	}
+}
+
+// IncludeStats adds the breaker's rolling statistics to a Snapshot, if
+// they are enabled with WithStats.
+func IncludeStats() SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.stats = true
+	}
+}
+
 // Snapshot returns a current snapshot of the circuit breaker.
 // This triggers lazy state evaluation.
-func (b *Breaker) Snapshot() BreakerState {
+func (b *Breaker) Snapshot(opts ...SnapshotOption) BreakerState {
+	var so snapshotOptions
+	for _, opt := range opts {
+		opt(&so)
+	}
+
 	b.stateMX.Lock()
 	from, to, transitioned := b.evaluateState()
+	bs := b.snapshot()
+	if so.config {
+		c := b.config()
+		bs.Config = &c
+	}
+	if so.errorCount {
+		n := int(b.tracker.size())
+		bs.ErrorCount = &n
+	}
+	if so.history && b.history != nil {
+		bs.History = b.history.list()
+	}
 	b.stateMX.Unlock()
 
-	if transitioned && b.onStateChange != nil {
-		b.onStateChange(b.name, from, to)
+	if so.stats {
+		bs.Stats = b.Stats()
 	}
 
+	b.notify(from, to, transitioned)
+
+	return bs
+}
+
+// snapshot builds a BreakerState from the current state.
+// Must be called with stateMX held.
+func (b *Breaker) snapshot() BreakerState {
 	state := State(atomic.LoadUint32(&b.state))
 
 	bs := BreakerState{
-		Name:  b.name,
-		State: state,
+		Name:     b.name,
+		State:    state,
+		Forced:   atomic.LoadUint32(&b.forced) == 1,
+		Flapping: b.flap.flapping,
 	}
 
 	switch state {
@@ -482,8 +821,7 @@ func (b *Breaker) Snapshot() BreakerState {
 	case Throttled:
 		if since, ok := b.throttledStatus(); ok {
 			bs.Throttled = &since
-			ends := since.Add(b.backoff)
-			bs.BackOffEnds = &ends
+			b.throttleDetail(&bs, since)
 		}
 	case Open:
 		openedAt, lockedAt, isOpen, isLocked := b.openAndLockedStatus()
@@ -491,7 +829,7 @@ func (b *Breaker) Snapshot() BreakerState {
 			bs.Opened = &openedAt
 		}
 		if isLocked {
-			ends := lockedAt.Add(b.lockout)
+			ends := lockedAt.Add(b.lockedDuration())
 			bs.LockoutEnds = &ends
 		}
 	}
@@ -499,6 +837,154 @@ func (b *Breaker) Snapshot() BreakerState {
 	return bs
 }
 
+// throttleDetail reports how a breaker throttled since the given time
+// recovers: the admission in ramp-up mode, or else the end of the backoff.
+// Must be called with stateMX held.
+func (b *Breaker) throttleDetail(bs *BreakerState, since time.Time) {
+	if b.rampStep > 0 {
+		bs.Admission = b.admit
+		return
+	}
+	ends := since.Add(b.backoff)
+	bs.BackOffEnds = &ends
+}
+
+// ForceOpen transitions the breaker to Open and pins it there, so it
+// rejects every request regardless of its error count or lockout,
+// until Reset is called.
+func (b *Breaker) ForceOpen() {
+	b.force(internalOpen)
+}
+
+// ForceClose transitions the breaker to Closed and pins it there, so it
+// allows every request regardless of its error count, until Reset is called.
+// The pin also takes precedence over an open parent and over peers that
+// report the breaker open through a state store.
+// Errors are still recorded while the breaker is pinned.
+func (b *Breaker) ForceClose() {
+	b.force(internalClosed)
+}
+
+// Reset removes any pin set by ForceOpen or ForceClose, clears all
+// recorded errors and transitions the breaker to Closed.
+func (b *Breaker) Reset() {
+	b.stateMX.Lock()
+	atomic.StoreUint32(&b.forced, 0)
+	b.tracker.reset(true)
+	from := State(atomic.LoadUint32(&b.state))
+	_, changed := b.changeStateTo(internalClosed, reasonReset)
+	b.stateMX.Unlock()
+
+	if changed && b.onStateChange != nil {
+		b.onStateChange(b.name, from, Closed)
+	}
+}
+
+func (b *Breaker) force(to uint32) {
+	b.stateMX.Lock()
+	atomic.StoreUint32(&b.forced, 1)
+	from := State(atomic.LoadUint32(&b.state))
+	reason := reasonForcedClosed
+	if to == internalOpen {
+		reason = reasonForcedOpen
+	}
+	_, changed := b.changeStateTo(to, reason)
+	b.stateMX.Unlock()
+
+	if changed && b.onStateChange != nil {
+		b.onStateChange(b.name, from, State(to))
+	}
+}
+
+// Reconfigure applies new settings to a live breaker without losing its
+// state. The new settings are validated like those passed to NewBreaker,
+// and if any is invalid the breaker is left unchanged. Only the timeout,
+// backoff, window, threshold, lockout, lockout policy, ramp-up, estimation
+// or probability function, deterministic throttle, opening-resets, strict
+// and stats window settings are applied; all other options are ignored.
+// A new lockout applies from the next time the breaker opens, and a new
+// stats window restarts the stats.
+// Unset values are kept, and zero values fall back to the defaults
+// used by NewBreaker. The error window is resized in place, so recorded
+// errors that fall within the new window are kept.
+// The change is applied atomically with respect to concurrent calls to Run
+// and Allow, and the WithOnConfigChange callback is invoked afterwards.
+func (b *Breaker) Reconfigure(opts ...Option) error {
+	if b.tracker == nil {
+		return ErrNotInitialized
+	}
+
+	b.stateMX.Lock()
+	next, err := b.reconfigured(opts...)
+	if err != nil {
+		b.stateMX.Unlock()
+		return err
+	}
+
+	b.openingResets = next.openingResets
+	b.evenThrottle = next.evenThrottle
+	b.strict = next.strict
+	atomic.StoreUint32(&b.threshold, next.threshold)
+	atomic.StoreInt64((*int64)(&b.timeout), int64(next.timeout))
+	b.backoff = next.backoff
+	b.lockout = next.lockout
+	b.lockoutPolicy = next.lockoutPolicy
+	if next.rampStep > 0 && b.admit == 0 {
+		// ramp up from the first step if enabled while throttled
+		b.admit = next.rampStep
+	}
+	atomic.StoreUint32(&b.rampStep, next.rampStep)
+	b.window = next.window
+	b.estimate = next.estimate
+	b.probability = next.probability
+	b.estimationName = next.estimationName
+	b.tracker.resize(next.window)
+	if next.statsWindow != b.statsWindow {
+		b.statsWindow = next.statsWindow
+		var stats *rollingStats
+		if next.statsWindow > 0 {
+			stats = newRollingStats(next.statsWindow)
+		}
+		b.stats.Store(stats)
+	}
+	b.stateMX.Unlock()
+
+	if b.onConfigChange != nil {
+		b.onConfigChange(b.name)
+	}
+	return nil
+}
+
+// reconfigured returns the settings that result from applying opts to the
+// breaker's current ones, validated and with defaults applied, without
+// changing the breaker. Must be called with stateMX held.
+func (b *Breaker) reconfigured(opts ...Option) (*Breaker, error) {
+	next := &Breaker{
+		strict:         b.strict,
+		openingResets:  b.openingResets,
+		evenThrottle:   b.evenThrottle,
+		threshold:      b.threshold,
+		timeout:        b.timeout,
+		backoff:        b.backoff,
+		lockout:        b.lockout,
+		lockoutPolicy:  b.lockoutPolicy,
+		rampStep:       b.rampStep,
+		window:         b.window,
+		estimate:       b.estimate,
+		probability:    b.probability,
+		estimationName: b.estimationName,
+		statsWindow:    b.statsWindow,
+	}
+	for _, opt := range opts {
+		opt(next)
+	}
+	if err := next.validate(); err != nil {
+		return nil, err
+	}
+	next.applyDefaults()
+	return next, nil
+}
+
 func timeFromNS(ns int64) time.Time {
 	u := ns / 1e9
 	return time.Unix(u, ns-u*1e9)
@@ -1,20 +1,83 @@
 package circuit
 
-import "sync"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
 
 type BreakerBox struct {
 	breakers    sync.Map
 	createMu    sync.Mutex // serializes Create/LoadOrCreate to prevent TOCTOU races
 	stateChange chan BreakerState
+
+	subMu       sync.RWMutex // protects subscribers; held for reading while forwarding
+	subscribers map[chan BreakerState]struct{}
 }
 
 // NewBreakerBox will return a BreakerBox with all internals properly configured.
 func NewBreakerBox() *BreakerBox {
 	return &BreakerBox{
 		stateChange: make(chan BreakerState, 16),
+		subscribers: make(map[chan BreakerState]struct{}),
+	}
+}
+
+// forward sends a state change to the box channel and to every subscriber.
+// Sends never block; a change is dropped for any channel that is full.
+func (bb *BreakerBox) forward(state BreakerState) {
+	select {
+	case bb.stateChange <- state:
+	default:
+	}
+
+	bb.subMu.RLock()
+	for ch := range bb.subscribers {
+		select {
+		case ch <- state:
+		default:
+		}
+	}
+	bb.subMu.RUnlock()
+}
+
+// Subscribe returns a channel that receives every state change forwarded to
+// the box, independently of StateChange and of other subscribers. Changes are
+// dropped if the channel's buffer is full. Call the returned function to
+// unsubscribe; the channel is then closed.
+func (bb *BreakerBox) Subscribe(buffer int) (<-chan BreakerState, func()) {
+	ch := make(chan BreakerState, buffer)
+	bb.subMu.Lock()
+	if bb.subscribers == nil {
+		bb.subscribers = make(map[chan BreakerState]struct{})
+	}
+	bb.subscribers[ch] = struct{}{}
+	bb.subMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			bb.subMu.Lock()
+			delete(bb.subscribers, ch)
+			bb.subMu.Unlock()
+			close(ch)
+		})
 	}
 }
 
+// Breakers returns every breaker in the box, sorted by name.
+func (bb *BreakerBox) Breakers() []*Breaker {
+	var breakers []*Breaker
+	bb.breakers.Range(func(_, v any) bool {
+		breakers = append(breakers, v.(*Breaker))
+		return true
+	})
+	sort.Slice(breakers, func(i, j int) bool {
+		return breakers[i].name < breakers[j].name
+	})
+	return breakers
+}
+
 // StateChange exposes the breaker state channel of the box.
 // State changes from all breakers created via Create are forwarded here
 // with full timing information (Opened, LockoutEnds, Throttled, etc).
@@ -53,7 +116,7 @@ func (bb *BreakerBox) Create(opts ...Option) (*Breaker, error) {
 	if b.name == "" {
 		return nil, ErrUnnamedBreaker
 	}
-	b.boxStateChange = bb.stateChange
+	b.box = bb
 	bb.breakers.Store(b.name, b)
 
 	return b, nil
@@ -80,3 +143,18 @@ func (bb *BreakerBox) LoadOrCreate(name string, opts ...Option) (*Breaker, error
 	opts = append([]Option{WithName(name)}, opts...)
 	return bb.Create(opts...)
 }
+
+// CreateChild will generate a new circuit breaker whose parent is the breaker
+// in the box with the given name. Failures in the child also count toward the
+// parent, and an open parent rejects requests on all of its children.
+// If the parent does not exist, an error wrapping ErrParentNotFound
+// and naming the parent is returned.
+func (bb *BreakerBox) CreateChild(parent string, opts ...Option) (*Breaker, error) {
+	p := bb.Load(parent)
+	if p == nil {
+		return nil, fmt.Errorf("%w: %q", ErrParentNotFound, parent)
+	}
+
+	opts = append(opts, WithParent(p))
+	return bb.Create(opts...)
+}
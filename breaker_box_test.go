@@ -3,6 +3,7 @@ package circuit
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -187,10 +188,46 @@ func TestBreakerBox(t *testing.T) {
 		})
 	})
 
+	t.Run("CreateChild", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("success", func(t *testing.T) {
+			t.Parallel()
+			bb := NewBreakerBox()
+			parent, _ := bb.Create(WithName("host"))
+			child, err := bb.CreateChild("host", WithName("host/users"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if child.Parent() != parent {
+				t.Fatal("expected child to be linked to parent")
+			}
+			if bb.Load("host/users") != child {
+				t.Fatal("expected child to be stored in the box")
+			}
+		})
+
+		t.Run("missing parent", func(t *testing.T) {
+			t.Parallel()
+			bb := NewBreakerBox()
+			_, err := bb.CreateChild("nope", WithName("child"))
+			if !errors.Is(err, ErrParentNotFound) {
+				t.Fatalf("expected ErrParentNotFound, got %v", err)
+			}
+			if !strings.Contains(err.Error(), `"nope"`) {
+				t.Fatalf("expected the error to name the parent, got %v", err)
+			}
+			var circErr Error
+			if errors.As(err, &circErr) && (circErr.BreakerName != "" || circErr.State != 0) {
+				t.Fatalf("expected no breaker context for a missing parent, got %+v", circErr)
+			}
+		})
+	})
+
 	t.Run("state change forwarding", func(t *testing.T) {
 		t.Parallel()
 		bb := NewBreakerBox()
-		b, _ := bb.Create(WithName("test"), WithWindow(50*time.Millisecond), WithLockOut(time.Second))
+		b, _ := bb.Create(WithName("test"), WithWindow(time.Second), WithLockOut(time.Second))
 
 		// Drain the initial "closed" state from breaker's own channel
 		select {
@@ -222,6 +259,56 @@ func TestBreakerBox(t *testing.T) {
 		}
 	})
 
+	t.Run("Subscribe", func(t *testing.T) {
+		t.Parallel()
+		bb := NewBreakerBox()
+		b, _ := bb.Create(WithName("test"), WithLockOut(time.Second))
+		first, cancelFirst := bb.Subscribe(4)
+		second, cancelSecond := bb.Subscribe(4)
+		defer cancelSecond()
+
+		b.tracker.incr()
+		b.State() // closed -> open
+
+		for _, ch := range []<-chan BreakerState{first, second, bb.StateChange()} {
+			select {
+			case state := <-ch:
+				if state.State != Open {
+					t.Fatalf("expected Open state, got %s", state.State)
+				}
+			case <-time.After(100 * time.Millisecond):
+				t.Fatal("timed out waiting for state change")
+			}
+		}
+
+		cancelFirst()
+		cancelFirst() // idempotent
+		if _, ok := <-first; ok {
+			t.Fatal("expected channel to be closed after unsubscribe")
+		}
+		b.Reset()
+		select {
+		case <-second:
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("expected remaining subscriber to receive state change")
+		}
+	})
+
+	t.Run("Breakers", func(t *testing.T) {
+		t.Parallel()
+		bb := NewBreakerBox()
+		bb.Create(WithName("c"))
+		bb.Create(WithName("a"))
+		bb.Create(WithName("b"))
+		var names []string
+		for _, b := range bb.Breakers() {
+			names = append(names, b.Name())
+		}
+		if len(names) != 3 || names[0] != "a" || names[1] != "b" || names[2] != "c" {
+			t.Fatalf("expected sorted breakers, got %v", names)
+		}
+	})
+
 	t.Run("state change with user hook", func(t *testing.T) {
 		t.Parallel()
 		bb := NewBreakerBox()
@@ -12,6 +12,21 @@ type BreakerState struct {
 	LockoutEnds *time.Time `json:"lockout_ends,omitempty"`
 	Throttled   *time.Time `json:"throttled,omitempty"`
 	BackOffEnds *time.Time `json:"backoff_ends,omitempty"`
+	Forced      bool       `json:"forced,omitempty"`
+	Flapping    bool       `json:"flapping,omitempty"`
+	// Admission is the percentage of requests admitted by a throttled
+	// breaker in ramp-up mode. It is zero otherwise.
+	Admission uint32 `json:"admission,omitempty"`
+
+	// Config is only set by Snapshot when IncludeConfig is passed.
+	Config *BreakerConfig `json:"config,omitempty"`
+	// ErrorCount is only set by Snapshot when IncludeErrorCount is passed.
+	ErrorCount *int `json:"error_count,omitempty"`
+	// History is only set by Snapshot when IncludeHistory is passed.
+	History []Transition `json:"history,omitempty"`
+	// Stats is only set by Snapshot when IncludeStats is passed and stats
+	// are enabled with WithStats.
+	Stats *Stats `json:"stats,omitempty"`
 }
 
 func (bs BreakerState) String() string {
@@ -2,6 +2,8 @@ package circuit
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -174,7 +176,7 @@ func TestChangeStateTo_SameState(t *testing.T) {
 	b := mustNewBreaker(t, WithName("same-state"))
 	// Breaker starts in closed (0), calling changeStateTo(internalClosed) should be a no-op
 	b.stateMX.Lock()
-	_, changed := b.changeStateTo(internalClosed)
+	_, changed := b.changeStateTo(internalClosed, reasonReset)
 	b.stateMX.Unlock()
 	if changed {
 		t.Fatal("expected no transition when changing to same state")
@@ -196,3 +198,22 @@ func TestSnapshot_TransitionWithOnStateChange(t *testing.T) {
 		t.Fatal("expected onStateChange to be called during Snapshot")
 	}
 }
+
+func TestSnapshot_ConfigJSON(t *testing.T) {
+	t.Parallel()
+	b := mustNewBreaker(t, WithName("snap-json"), WithBackOff(30*time.Second), WithEstimationFunc(Exponential))
+	data, err := json.Marshal(b.Snapshot(IncludeConfig()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{`"backoff":"30s"`, `"estimation":"exponential"`, `"timeout":"10s"`} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("expected %s in %s", want, data)
+		}
+	}
+
+	data, _ = json.Marshal(b.Snapshot())
+	if strings.Contains(string(data), `"config"`) {
+		t.Fatalf("expected config to be omitted, got %s", data)
+	}
+}
@@ -85,13 +85,13 @@ func TestBreaker(t *testing.T) {
 
 		t.Run("open to throttled after lockout expires", func(t *testing.T) {
 			t.Parallel()
-			// Window must be shorter than lockout so errors expire before lockout ends
-			b := mustNewBreaker(t, WithLockOut(100*time.Millisecond), WithWindow(50*time.Millisecond))
+			// The window is as long as the lockout, so errors expire when it ends
+			b := mustNewBreaker(t, WithLockOut(50*time.Millisecond), WithWindow(50*time.Millisecond))
 			b.tracker.incr()
 			b.State() // trigger closed -> open
 
-			time.Sleep(150 * time.Millisecond)
-			// error expired (50ms window), lockout expired (100ms)
+			time.Sleep(100 * time.Millisecond)
+			// error expired (50ms window), lockout expired (50ms)
 			if b.State() != Throttled {
 				t.Fatalf("expected Throttled after lockout, got %s", b.State())
 			}
@@ -145,7 +145,7 @@ func TestBreaker(t *testing.T) {
 			var mu sync.Mutex
 			transitions := make([]string, 0)
 			b := mustNewBreaker(t,
-				WithLockOut(100*time.Millisecond),
+				WithLockOut(50*time.Millisecond),
 				WithBackOff(100*time.Millisecond),
 				WithWindow(50*time.Millisecond),
 				WithOnStateChange(func(name string, from, to State) {
@@ -159,8 +159,8 @@ func TestBreaker(t *testing.T) {
 			b.tracker.incr()
 			b.State()
 
-			// wait for lockout (100ms) + error eviction (50ms window)
-			time.Sleep(150 * time.Millisecond)
+			// wait for lockout (50ms) + error eviction (50ms window)
+			time.Sleep(100 * time.Millisecond)
 			// open -> throttled (lockout expired, errors evicted)
 			b.State()
 
@@ -365,6 +365,43 @@ func TestBreaker(t *testing.T) {
 		})
 	})
 
+	t.Run("estimation panic releases the lock", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t,
+			WithBackOff(time.Hour),
+			// validation only samples every tenth tick
+			WithEstimationFunc(func(tick int) uint32 {
+				if tick == 55 {
+					panic("bad tick")
+				}
+				return 0
+			}),
+		)
+		cycle(b, Open)
+		cycle(b, Throttled)
+		atomic.StoreInt64(&b.throttledSince, time.Now().Add(-33*time.Minute).UnixNano())
+
+		func() {
+			defer func() {
+				if r := recover(); r != "bad tick" {
+					t.Fatalf("expected the panic to propagate, got %v", r)
+				}
+			}()
+			b.Allow(context.Background())
+		}()
+
+		done := make(chan State)
+		go func() { done <- b.State() }()
+		select {
+		case state := <-done:
+			if state != Throttled {
+				t.Fatalf("expected throttled, got %s", state)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the lock to be released after the panic")
+		}
+	})
+
 	t.Run("Allow two-step", func(t *testing.T) {
 		t.Parallel()
 
@@ -537,3 +574,430 @@ func TestBreaker(t *testing.T) {
 		// No Close() calls needed — test passes without cleanup
 	})
 }
+
+func TestBreakerParent(t *testing.T) {
+	t.Parallel()
+
+	t.Run("child failures count toward parent", func(t *testing.T) {
+		t.Parallel()
+		parent := mustNewBreaker(t, WithName("host"), WithThreshold(1), WithLockOut(time.Second))
+		a := mustNewBreaker(t, WithName("host/a"), WithThreshold(5), WithParent(parent))
+		c := mustNewBreaker(t, WithName("host/c"), WithThreshold(5), WithParent(parent))
+
+		boom := errors.New("boom")
+		for _, child := range []*Breaker{a, c} {
+			Run(child, context.Background(), func(ctx context.Context) (int, error) {
+				return 0, boom
+			})
+		}
+
+		if parent.Size() != 2 {
+			t.Fatalf("expected parent to track 2 errors, got %d", parent.Size())
+		}
+		if a.Size() != 1 || c.Size() != 1 {
+			t.Fatalf("expected each child to track 1 error, got %d and %d", a.Size(), c.Size())
+		}
+		if parent.State() != Open {
+			t.Fatalf("expected parent Open, got %s", parent.State())
+		}
+	})
+
+	t.Run("open parent rejects children", func(t *testing.T) {
+		t.Parallel()
+		grandparent := mustNewBreaker(t, WithName("region"), WithLockOut(time.Second))
+		parent := mustNewBreaker(t, WithName("host"), WithThreshold(10), WithParent(grandparent))
+		child := mustNewBreaker(t, WithName("host/a"), WithThreshold(10), WithParent(parent))
+
+		grandparent.tracker.incr()
+
+		_, err := Run(child, context.Background(), func(ctx context.Context) (bool, error) {
+			return true, nil
+		})
+		if !errors.Is(err, ErrStateOpen) {
+			t.Fatalf("expected ErrStateOpen, got %v", err)
+		}
+		var circErr Error
+		if !errors.As(err, &circErr) {
+			t.Fatal("expected circuit.Error type")
+		}
+		if circErr.BreakerName != "region" {
+			t.Fatalf("expected rejection from 'region', got '%s'", circErr.BreakerName)
+		}
+		if child.State() != Closed {
+			t.Fatalf("expected child to stay Closed, got %s", child.State())
+		}
+	})
+
+	t.Run("parent rejection is recorded on child metrics", func(t *testing.T) {
+		t.Parallel()
+		m := &mockMetrics{}
+		parent := mustNewBreaker(t, WithName("host"), WithLockOut(time.Second))
+		child := mustNewBreaker(t, WithName("host/a"), WithParent(parent), WithMetrics(m))
+		parent.tracker.incr()
+
+		if _, err := child.Allow(context.Background()); !errors.Is(err, ErrStateOpen) {
+			t.Fatalf("expected ErrStateOpen, got %v", err)
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.rejected != 1 || m.lastRejState != Open {
+			t.Fatalf("expected 1 rejection in Open state, got %d in %s", m.rejected, m.lastRejState)
+		}
+	})
+
+	t.Run("parent accessor", func(t *testing.T) {
+		t.Parallel()
+		parent := mustNewBreaker(t, WithName("host"))
+		child := mustNewBreaker(t, WithName("host/a"), WithParent(parent))
+		if child.Parent() != parent {
+			t.Fatal("expected Parent to return the parent breaker")
+		}
+		if parent.Parent() != nil {
+			t.Fatal("expected root breaker to have no parent")
+		}
+	})
+}
+
+func TestBreakerReconfigure(t *testing.T) {
+	t.Parallel()
+
+	t.Run("applies new settings", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithName("re"), WithThreshold(5), WithLockOut(time.Second))
+		err := b.Reconfigure(
+			WithThreshold(10),
+			WithTimeout(time.Second),
+			WithBackOff(20*time.Second),
+			WithWindow(time.Minute),
+			WithLockOut(0),
+			WithEstimationFunc(Exponential),
+			WithOpeningResetsErrors(true),
+			WithName("ignored"),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.threshold != 10 || b.timeout != time.Second || b.backoff != 20*time.Second {
+			t.Fatalf("unexpected settings: threshold=%d timeout=%v backoff=%v", b.threshold, b.timeout, b.backoff)
+		}
+		if b.window != time.Minute || b.lockout != 0 || !b.openingResets {
+			t.Fatalf("unexpected settings: window=%v lockout=%v openingResets=%v", b.window, b.lockout, b.openingResets)
+		}
+		if b.estimate(1) != Exponential(1) {
+			t.Fatal("expected exponential estimation")
+		}
+		if b.name != "re" {
+			t.Fatalf("expected name to be unchanged, got %s", b.name)
+		}
+	})
+
+	t.Run("keeps unset values and applies defaults", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(5), WithBackOff(time.Second))
+		if err := b.Reconfigure(WithTimeout(0), WithWindow(time.Millisecond)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.threshold != 5 || b.backoff != time.Second {
+			t.Fatalf("expected unset values to be kept, got threshold=%d backoff=%v", b.threshold, b.backoff)
+		}
+		if b.timeout != DefaultTimeout {
+			t.Fatalf("expected default timeout, got %v", b.timeout)
+		}
+		if b.window != minimumWindow {
+			t.Fatalf("expected minimum window, got %v", b.window)
+		}
+	})
+
+	t.Run("keeps state and errors", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(1), WithLockOut(time.Second))
+		b.tracker.incr()
+		b.tracker.incr()
+		if b.State() != Open {
+			t.Fatalf("expected Open, got %s", b.State())
+		}
+		if err := b.Reconfigure(WithWindow(time.Hour)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.State() != Open {
+			t.Fatalf("expected breaker to stay Open, got %s", b.State())
+		}
+		if b.Size() != 2 {
+			t.Fatalf("expected errors to be kept, got %d", b.Size())
+		}
+	})
+
+	t.Run("shrinking window evicts old errors", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(5))
+		b.tracker.incr()
+		time.Sleep(60 * time.Millisecond)
+		b.tracker.incr()
+		if err := b.Reconfigure(WithWindow(50 * time.Millisecond)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.Size() != 1 {
+			t.Fatalf("expected only the recent error to remain, got %d", b.Size())
+		}
+	})
+
+	t.Run("threshold change triggers transition", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(5), WithLockOut(time.Second))
+		b.tracker.incr()
+		b.tracker.incr()
+		if b.State() != Closed {
+			t.Fatalf("expected Closed, got %s", b.State())
+		}
+		if err := b.Reconfigure(WithThreshold(1)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.State() != Open {
+			t.Fatalf("expected Open after lowering threshold, got %s", b.State())
+		}
+	})
+
+	t.Run("config change hook", func(t *testing.T) {
+		t.Parallel()
+		var called string
+		b := mustNewBreaker(t, WithName("hooked"), WithOnConfigChange(func(name string) {
+			called = name
+		}))
+		if err := b.Reconfigure(WithThreshold(3)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if called != "hooked" {
+			t.Fatalf("expected hook to be called with breaker name, got %q", called)
+		}
+	})
+
+	t.Run("not initialized", func(t *testing.T) {
+		t.Parallel()
+		b := &Breaker{}
+		if err := b.Reconfigure(); !errors.Is(err, ErrNotInitialized) {
+			t.Fatalf("expected ErrNotInitialized, got %v", err)
+		}
+	})
+
+	t.Run("concurrent with Run", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(1000), WithBackOff(50*time.Millisecond))
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					Run(b, context.Background(), func(ctx context.Context) (int, error) {
+						if j%2 == 0 {
+							return 0, errors.New("boom")
+						}
+						return j, nil
+					})
+					b.Snapshot()
+				}
+			}(i)
+		}
+		for i := 0; i < 50; i++ {
+			b.Reconfigure(
+				WithThreshold(uint32(i%3)),
+				WithTimeout(time.Duration(i+1)*time.Millisecond),
+				WithBackOff(time.Duration(i+10)*time.Millisecond),
+				WithEstimationFunc(EaseInOut),
+			)
+		}
+		wg.Wait()
+	})
+}
+
+func TestBreakerConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("effective values", func(t *testing.T) {
+		t.Parallel()
+		parent := mustNewBreaker(t, WithName("host"))
+		b := mustNewBreaker(t,
+			WithName("cfg"),
+			WithParent(parent),
+			WithBackOff(time.Millisecond),
+			WithThreshold(3),
+			WithLockOut(time.Second),
+			WithEstimationFunc(EaseInOut),
+			WithOpeningResetsErrors(true),
+		)
+		want := BreakerConfig{
+			Name:                "cfg",
+			Parent:              "host",
+			Timeout:             Duration(DefaultTimeout),
+			BackOff:             Duration(minimumBackoff),
+			Window:              Duration(DefaultWindow),
+			Threshold:           3,
+			LockOut:             Duration(time.Second),
+			Estimation:          "ease-in-out",
+			OpeningResetsErrors: true,
+		}
+		if got := b.Config(); got != want {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("custom estimation", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithEstimationFunc(func(int) uint32 { return 0 }))
+		c := b.Config()
+		if c.Estimation != CustomEstimation {
+			t.Fatalf("expected %q, got %q", CustomEstimation, c.Estimation)
+		}
+		if err := c.Validate(); err == nil {
+			t.Fatal("expected a custom estimation to fail validation")
+		}
+
+		RegisterEstimationFunc("test-config-zero", func(int) uint32 { return 0 })
+		bb, err := NewBreakerBoxFromConfig(BoxConfig{Breakers: []BreakerConfig{{Name: "a", Estimation: "test-config-zero"}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c = bb.Load("a").Config()
+		if err := bb.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{c}}); err != nil {
+			t.Fatalf("expected a registered estimation to round trip, got %v", err)
+		}
+	})
+
+	t.Run("reflects reconfiguration", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(3))
+		if err := b.Reconfigure(WithThreshold(8), WithEstimationFunc(Logarithmic)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c := b.Config()
+		if c.Threshold != 8 || c.Estimation != "logarithmic" {
+			t.Fatalf("unexpected config: %+v", c)
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithName("snap"))
+		if b.Snapshot().Config != nil {
+			t.Fatal("expected no config by default")
+		}
+		snap := b.Snapshot(IncludeConfig())
+		if snap.Config == nil || *snap.Config != b.Config() {
+			t.Fatalf("expected snapshot to include config, got %+v", snap.Config)
+		}
+	})
+}
+
+func TestBreakerThrottleCurve(t *testing.T) {
+	t.Parallel()
+	b := mustNewBreaker(t, WithEstimationFunc(Exponential))
+	curve := b.ThrottleCurve()
+	if len(curve) != 100 {
+		t.Fatalf("expected 100 points, got %d", len(curve))
+	}
+	for i, p := range curve {
+		if want := Exponential(i + 1); p != want {
+			t.Fatalf("tick %d: expected %d, got %d", i+1, want, p)
+		}
+	}
+
+	if err := b.Reconfigure(WithEstimationFunc(Linear)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := b.ThrottleCurve(); got[0] != Linear(1) || got[99] != Linear(100) {
+		t.Fatalf("expected curve to follow reconfiguration, got %v", got)
+	}
+}
+
+func TestBreakerForce(t *testing.T) {
+	t.Parallel()
+
+	t.Run("force open pins open", func(t *testing.T) {
+		t.Parallel()
+		var transitions []string
+		b := mustNewBreaker(t, WithOnStateChange(func(_ string, from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		}))
+		b.ForceOpen()
+		if _, err := b.Allow(context.Background()); !errors.Is(err, ErrStateOpen) {
+			t.Fatalf("expected ErrStateOpen, got %v", err)
+		}
+		// no errors and no lockout would normally throttle immediately
+		if b.State() != Open {
+			t.Fatalf("expected pinned Open, got %s", b.State())
+		}
+		if !b.Snapshot().Forced {
+			t.Fatal("expected snapshot to report forced")
+		}
+		if len(transitions) != 1 || transitions[0] != "closed->open" {
+			t.Fatalf("expected closed->open, got %v", transitions)
+		}
+	})
+
+	t.Run("force close pins closed", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithLockOut(time.Second))
+		b.tracker.incr()
+		if b.State() != Open {
+			t.Fatalf("expected Open, got %s", b.State())
+		}
+		b.ForceClose()
+		b.tracker.incr()
+		if _, err := b.Allow(context.Background()); err != nil {
+			t.Fatalf("expected pinned Closed to allow, got %v", err)
+		}
+		if b.State() != Closed {
+			t.Fatalf("expected pinned Closed, got %s", b.State())
+		}
+		if b.Size() != 2 {
+			t.Fatalf("expected errors to still be recorded, got %d", b.Size())
+		}
+	})
+
+	t.Run("force close wins over an open parent", func(t *testing.T) {
+		t.Parallel()
+		parent := mustNewBreaker(t, WithName("host"))
+		child := mustNewBreaker(t, WithName("host/a"), WithParent(parent))
+		parent.ForceOpen()
+		if _, err := child.Allow(context.Background()); !errors.Is(err, ErrStateOpen) {
+			t.Fatalf("expected the open parent to reject, got %v", err)
+		}
+		child.ForceClose()
+		if _, err := child.Allow(context.Background()); err != nil {
+			t.Fatalf("expected pinned Closed to allow, got %v", err)
+		}
+	})
+
+	t.Run("reset clears pin and errors", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(5))
+		b.tracker.incr()
+		b.ForceOpen()
+		b.Reset()
+		if b.State() != Closed {
+			t.Fatalf("expected Closed, got %s", b.State())
+		}
+		if b.Size() != 0 {
+			t.Fatalf("expected errors to be cleared, got %d", b.Size())
+		}
+		if b.Snapshot().Forced {
+			t.Fatal("expected pin to be removed")
+		}
+		b.tracker.incr()
+		if b.State() != Closed {
+			t.Fatalf("expected normal evaluation to resume, got %s", b.State())
+		}
+	})
+
+	t.Run("snapshot error count", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(5))
+		b.tracker.incr()
+		if b.Snapshot().ErrorCount != nil {
+			t.Fatal("expected no error count by default")
+		}
+		if n := b.Snapshot(IncludeErrorCount()).ErrorCount; n == nil || *n != 1 {
+			t.Fatalf("expected error count 1, got %v", n)
+		}
+	})
+}
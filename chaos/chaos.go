@@ -0,0 +1,323 @@
+// Package chaos injects faults into the calls a breaker protects, to test
+// that breakers open and recover, and that fallbacks work, as expected.
+//
+// An Injector decides the fate of each call: it can delay it, fail it at a
+// given rate, panic, or fail every call during an outage. Wrap applies an
+// Injector to a function passed to circuit.Run, and RoundTripper to an
+// http.RoundTripper. Every setting can be changed while calls are in
+// flight, so a test can break a dependency, watch the breaker open, and
+// heal it again:
+//
+//	in := chaos.New()
+//	client := &http.Client{Transport: chaos.RoundTripper(in, http.DefaultTransport)}
+//	in.StartOutage()
+//	// ... assert the breaker opens ...
+//	in.EndOutage()
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+// ErrInjected is the error returned for injected failures and outages,
+// unless another is set with WithError or SetError.
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Option configures an Injector.
+type Option func(*Injector)
+
+// WithErrorRate sets the probability, from 0 to 1, that a call fails.
+func WithErrorRate(rate float64) Option {
+	return func(in *Injector) {
+		in.errorRate = clamp(rate)
+	}
+}
+
+// WithError sets the error returned for injected failures and outages.
+func WithError(err error) Option {
+	return func(in *Injector) {
+		in.err = err
+	}
+}
+
+// WithLatency delays every call by d, plus or minus up to jitter.
+func WithLatency(d, jitter time.Duration) Option {
+	return func(in *Injector) {
+		in.latency, in.jitter = d, jitter
+	}
+}
+
+// WithPanicRate sets the probability, from 0 to 1, that a call panics
+// with v.
+func WithPanicRate(rate float64, v any) Option {
+	return func(in *Injector) {
+		in.panicRate, in.panicValue = clamp(rate), v
+	}
+}
+
+// WithOutage schedules an outage from start for d, during which every
+// call fails.
+func WithOutage(start time.Time, d time.Duration) Option {
+	return func(in *Injector) {
+		in.outages = append(in.outages, outage{start: start, end: start.Add(d)})
+	}
+}
+
+// WithClock sets the clock used for scheduled outages, e.g. the same
+// virtual clock as the breaker under test. Latency is always real time.
+func WithClock(c circuit.Clock) Option {
+	return func(in *Injector) {
+		in.clock = c
+	}
+}
+
+// WithRand sets the source of randomness for failures, panics and
+// latency jitter, so that a test can replay them. By default, the global
+// source in math/rand/v2 is used.
+func WithRand(src rand.Source) Option {
+	return func(in *Injector) {
+		in.rng = nil
+		if src != nil {
+			in.rng = rand.New(src)
+		}
+	}
+}
+
+// outage is a window during which every call fails.
+type outage struct {
+	start, end time.Time
+}
+
+// Counts tallies the calls an Injector has seen.
+type Counts struct {
+	Calls    int // calls seen
+	Failures int // calls failed, by the error rate or an outage
+	Panics   int // calls panicked
+}
+
+// Injector decides which faults to inject into each call.
+// It is safe for concurrent use.
+type Injector struct {
+	mu         sync.Mutex
+	errorRate  float64
+	err        error
+	latency    time.Duration
+	jitter     time.Duration
+	panicRate  float64
+	panicValue any
+	outages    []outage
+	down       bool // in a manual outage
+	clock      circuit.Clock
+	rng        *rand.Rand
+	counts     Counts
+}
+
+// New returns an Injector configured with opts. With no options, it
+// injects nothing.
+func New(opts ...Option) *Injector {
+	in := &Injector{err: ErrInjected}
+	for _, opt := range opts {
+		opt(in)
+	}
+	return in
+}
+
+// Set applies opts to a live Injector. Calls already waiting out their
+// latency are not affected.
+func (in *Injector) Set(opts ...Option) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for _, opt := range opts {
+		opt(in)
+	}
+}
+
+// SetErrorRate sets the probability, from 0 to 1, that a call fails.
+func (in *Injector) SetErrorRate(rate float64) {
+	in.Set(WithErrorRate(rate))
+}
+
+// SetError sets the error returned for injected failures and outages.
+func (in *Injector) SetError(err error) {
+	in.Set(WithError(err))
+}
+
+// SetLatency delays every call by d, plus or minus up to jitter.
+func (in *Injector) SetLatency(d, jitter time.Duration) {
+	in.Set(WithLatency(d, jitter))
+}
+
+// SetPanicRate sets the probability, from 0 to 1, that a call panics with v.
+func (in *Injector) SetPanicRate(rate float64, v any) {
+	in.Set(WithPanicRate(rate, v))
+}
+
+// ScheduleOutage schedules an outage from start for d.
+func (in *Injector) ScheduleOutage(start time.Time, d time.Duration) {
+	in.Set(WithOutage(start, d))
+}
+
+// StartOutage fails every call until EndOutage is called.
+func (in *Injector) StartOutage() {
+	in.mu.Lock()
+	in.down = true
+	in.mu.Unlock()
+}
+
+// EndOutage ends an outage started with StartOutage, and cancels any
+// scheduled outages.
+func (in *Injector) EndOutage() {
+	in.mu.Lock()
+	in.down = false
+	in.outages = nil
+	in.mu.Unlock()
+}
+
+// Reset stops injecting faults, keeping the error, clock and random
+// source, and clears the counts.
+func (in *Injector) Reset() {
+	in.mu.Lock()
+	in.errorRate, in.panicRate, in.panicValue = 0, 0, nil
+	in.latency, in.jitter = 0, 0
+	in.outages, in.down = nil, false
+	in.counts = Counts{}
+	in.mu.Unlock()
+}
+
+// Counts returns the calls seen so far.
+func (in *Injector) Counts() Counts {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.counts
+}
+
+// Inject applies the configured faults to one call: it waits out any
+// latency, then panics, or returns the injected error, or nil if the call
+// should proceed. If ctx is done while waiting, it returns ctx's error.
+func (in *Injector) Inject(ctx context.Context) error {
+	in.mu.Lock()
+	in.counts.Calls++
+	delay := in.latency
+	if in.jitter > 0 {
+		delay += time.Duration(in.float64()*float64(2*in.jitter)) - in.jitter
+	}
+	var fail, panics bool
+	switch {
+	case in.inOutage():
+		fail = true
+	case in.panicRate > 0 && in.float64() < in.panicRate:
+		panics = true
+	case in.errorRate > 0 && in.float64() < in.errorRate:
+		fail = true
+	}
+	if fail {
+		in.counts.Failures++
+	}
+	if panics {
+		in.counts.Panics++
+	}
+	err, v := in.err, in.panicValue
+	in.mu.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	if panics {
+		panic(v)
+	}
+	if fail {
+		return err
+	}
+	return nil
+}
+
+// inOutage reports whether calls fail because of an outage, and drops
+// scheduled outages that have ended. Must be called with mu held.
+func (in *Injector) inOutage() bool {
+	if in.down {
+		return true
+	}
+	if len(in.outages) == 0 {
+		return false
+	}
+	now := time.Now()
+	if in.clock != nil {
+		now = in.clock.Now()
+	}
+	active := false
+	kept := in.outages[:0]
+	for _, o := range in.outages {
+		if now.Before(o.end) {
+			kept = append(kept, o)
+			active = active || !now.Before(o.start)
+		}
+	}
+	in.outages = kept
+	return active
+}
+
+// float64 returns a random number in [0, 1). Must be called with mu held.
+func (in *Injector) float64() float64 {
+	if in.rng != nil {
+		return in.rng.Float64()
+	}
+	return rand.Float64()
+}
+
+func clamp(rate float64) float64 {
+	if !(rate > 0) { // also catches NaN
+		return 0
+	}
+	return min(rate, 1)
+}
+
+// Wrap returns fn with the faults of in injected before it is called, for
+// use with circuit.Run. An injected failure is returned without calling fn.
+func Wrap[T any](in *Injector, fn func(context.Context) (T, error)) func(context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		if err := in.Inject(ctx); err != nil {
+			var zero T
+			return zero, err
+		}
+		return fn(ctx)
+	}
+}
+
+// RoundTripper returns next with the faults of in injected before each
+// request is sent. An injected failure is returned as the transport error,
+// without sending the request; as http.RoundTripper requires, the request
+// body is still closed. If next is nil, http.DefaultTransport is used.
+func RoundTripper(in *Injector, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripper{in: in, next: next}
+}
+
+type roundTripper struct {
+	in   *Injector
+	next http.RoundTripper
+}
+
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := rt.in.Inject(req.Context()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return rt.next.RoundTrip(req)
+}
@@ -0,0 +1,259 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/schigh/circuit"
+	"github.com/schigh/circuit/simulate"
+)
+
+func ok(context.Context) (string, error) { return "ok", nil }
+
+func TestInjector(t *testing.T) {
+	t.Parallel()
+
+	t.Run("injects nothing by default", func(t *testing.T) {
+		t.Parallel()
+		in := New()
+		for i := 0; i < 100; i++ {
+			if v, err := Wrap(in, ok)(context.Background()); err != nil || v != "ok" {
+				t.Fatalf("expected the call to pass through, got %q, %v", v, err)
+			}
+		}
+		if c := in.Counts(); c != (Counts{Calls: 100}) {
+			t.Fatalf("unexpected counts %+v", c)
+		}
+	})
+
+	t.Run("error rate", func(t *testing.T) {
+		t.Parallel()
+		in := New(WithErrorRate(0.3), WithRand(rand.NewPCG(1, 1)))
+		failed := 0
+		for i := 0; i < 1000; i++ {
+			if _, err := Wrap(in, ok)(context.Background()); errors.Is(err, ErrInjected) {
+				failed++
+			}
+		}
+		if failed < 250 || failed > 350 {
+			t.Fatalf("expected about 300 failures, got %d", failed)
+		}
+		if c := in.Counts(); c.Failures != failed {
+			t.Fatalf("expected %d failures counted, got %+v", failed, c)
+		}
+
+		boom := errors.New("boom")
+		in.Set(WithErrorRate(1), WithError(boom))
+		if _, err := Wrap(in, ok)(context.Background()); err != boom {
+			t.Fatalf("expected the configured error, got %v", err)
+		}
+		in.SetErrorRate(0)
+		if _, err := Wrap(in, ok)(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("does not call fn on failure", func(t *testing.T) {
+		t.Parallel()
+		in := New(WithErrorRate(1))
+		called := false
+		_, _ = Wrap(in, func(context.Context) (int, error) {
+			called = true
+			return 0, nil
+		})(context.Background())
+		if called {
+			t.Fatal("expected fn not to be called")
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		t.Parallel()
+		in := New(WithLatency(20*time.Millisecond, 5*time.Millisecond))
+		start := time.Now()
+		if err := in.Inject(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+			t.Fatalf("expected the call to be delayed, took %v", elapsed)
+		}
+
+		in.SetLatency(time.Hour, 0)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := in.Inject(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the context's error, got %v", err)
+		}
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		t.Parallel()
+		in := New(WithPanicRate(1, "kaboom"))
+		b, err := circuit.NewBreaker()
+		if err != nil {
+			t.Fatalf("NewBreaker failed: %v", err)
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != "kaboom" {
+					t.Fatalf("expected the injected panic, got %v", r)
+				}
+			}()
+			_, _ = circuit.Run(b, context.Background(), Wrap(in, ok))
+		}()
+		if b.State() != circuit.Open {
+			t.Fatal("expected the panic to count as a failure")
+		}
+		if c := in.Counts(); c.Panics != 1 {
+			t.Fatalf("expected a panic counted, got %+v", c)
+		}
+	})
+
+	t.Run("scheduled outage", func(t *testing.T) {
+		t.Parallel()
+		start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := simulate.NewClock(start)
+		in := New(WithClock(clock), WithOutage(start.Add(time.Minute), time.Minute))
+		in.ScheduleOutage(start.Add(5*time.Minute), time.Minute)
+
+		for _, tc := range []struct {
+			at   time.Duration
+			fail bool
+		}{
+			{0, false},
+			{time.Minute, true},
+			{90 * time.Second, true},
+			{2 * time.Minute, false},
+			{5*time.Minute + time.Second, true},
+			{6 * time.Minute, false},
+		} {
+			clock.Set(start.Add(tc.at))
+			if err := in.Inject(context.Background()); (err != nil) != tc.fail {
+				t.Fatalf("at %v: expected failure %v, got %v", tc.at, tc.fail, err)
+			}
+		}
+		if len(in.outages) != 0 {
+			t.Fatalf("expected ended outages to be dropped, got %d", len(in.outages))
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		t.Parallel()
+		boom := errors.New("boom")
+		in := New(WithErrorRate(1), WithPanicRate(1, "kaboom"), WithLatency(time.Hour, 0), WithError(boom))
+		in.StartOutage()
+		in.Reset()
+		if err := in.Inject(context.Background()); err != nil {
+			t.Fatalf("expected no faults after reset, got %v", err)
+		}
+		in.StartOutage()
+		if err := in.Inject(context.Background()); err != boom {
+			t.Fatalf("expected the error to be kept, got %v", err)
+		}
+	})
+}
+
+func TestBreakerRecovers(t *testing.T) {
+	t.Parallel()
+
+	clock := simulate.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b, err := circuit.NewBreaker(
+		circuit.WithClock(clock),
+		circuit.WithThreshold(2),
+		circuit.WithWindow(time.Minute),
+		circuit.WithBackOff(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("NewBreaker failed: %v", err)
+	}
+	in := New()
+	call := Wrap(in, ok)
+
+	in.StartOutage()
+	for i := 0; i < 3; i++ {
+		_, _ = circuit.Run(b, context.Background(), call)
+	}
+	if b.State() != circuit.Open {
+		t.Fatal("expected the outage to open the breaker")
+	}
+	if _, err := circuit.Run(b, context.Background(), call); !errors.Is(err, circuit.ErrStateOpen) {
+		t.Fatalf("expected the open breaker to reject, got %v", err)
+	}
+
+	in.EndOutage()
+	clock.Advance(time.Minute + time.Second)
+	if b.State() != circuit.Throttled {
+		t.Fatal("expected the breaker to throttle once errors leave the window")
+	}
+	clock.Advance(time.Minute)
+	if v, err := circuit.Run(b, context.Background(), call); err != nil || v != "ok" {
+		t.Fatalf("expected the call to succeed, got %q, %v", v, err)
+	}
+	if b.State() != circuit.Closed {
+		t.Fatal("expected the breaker to close")
+	}
+}
+
+func TestRoundTripper(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	in := New()
+	client := &http.Client{Transport: RoundTripper(in, srv.Client().Transport)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	in.StartOutage()
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrInjected) {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected the failed request not to be sent, got %d requests", n)
+	}
+
+	in.Reset()
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected the outage to end on reset, got %v", err)
+	}
+	resp.Body.Close()
+	if c := in.Counts(); c != (Counts{Calls: 1}) {
+		t.Fatalf("expected the counts to restart, got %+v", c)
+	}
+
+	in.StartOutage()
+	body := &closeTracker{Reader: strings.NewReader("payload")}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, body)
+	if _, err := client.Do(req); !errors.Is(err, ErrInjected) {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+	if !body.closed {
+		t.Fatal("expected the request body to be closed")
+	}
+}
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
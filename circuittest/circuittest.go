@@ -0,0 +1,146 @@
+// Package circuittest provides helpers for testing code that depends on
+// circuit breakers: a recording MetricsCollector, a recorder for state
+// changes, and functions to trip a breaker and wait for its state.
+//
+//	m := &circuittest.Metrics{}
+//	rec := &circuittest.Recorder{}
+//	b, _ := circuit.NewBreaker(circuit.WithMetrics(m), circuit.WithOnStateChange(rec.Record))
+//
+//	if err := circuittest.TripBreaker(b); err != nil {
+//		t.Fatal(err)
+//	}
+//	circuittest.AssertEventuallyState(t, b, circuit.Open, time.Second)
+package circuittest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+// ErrTripped is the failure reported to a breaker by TripBreaker.
+var ErrTripped = errors.New("circuittest: tripped")
+
+// pollInterval is how often AssertEventuallyState checks the state.
+const pollInterval = 5 * time.Millisecond
+
+// TB is the subset of testing.TB used by the assertions.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// AssertEventuallyState fails the test unless b reaches want within
+// timeout. The state is polled, so that lazy transitions such as the end
+// of a lockout are evaluated.
+func AssertEventuallyState(t TB, b *circuit.Breaker, want circuit.State, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		got := b.State()
+		if got == want {
+			return
+		}
+		if !time.Now().Before(deadline) {
+			t.Fatalf("breaker %q: expected state %s within %v, got %s", b.Name(), want, timeout, got)
+			return
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// TripBreaker reports failures to b until it opens, which takes one more
+// failure than its threshold when closed. Requests the breaker rejects
+// while throttled are retried. It returns an error if b does not open,
+// e.g. because its error classifiers ignore ErrTripped.
+func TripBreaker(b *circuit.Breaker) error {
+	threshold := int(b.Config().Threshold)
+	failures := 0
+	// a throttled breaker may reject most requests, so allow for retries
+	for attempts := 0; attempts < 100*(threshold+1); attempts++ {
+		if b.State() == circuit.Open {
+			return nil
+		}
+		done, err := b.Allow(context.Background())
+		if err != nil {
+			continue
+		}
+		done(ErrTripped)
+		failures++
+		if failures > threshold && b.State() != circuit.Open {
+			break // the failures are not being counted
+		}
+	}
+	if b.State() == circuit.Open {
+		return nil
+	}
+	return fmt.Errorf("circuittest: breaker %q did not open after %d failures", b.Name(), failures)
+}
+
+// DrainStateChanges returns the states queued on ch, oldest first, without
+// blocking, e.g. to discard notifications before the part of a test that
+// checks them. It works with both Breaker.StateChange and
+// BreakerBox.StateChange.
+func DrainStateChanges(ch <-chan circuit.BreakerState) []circuit.BreakerState {
+	var out []circuit.BreakerState
+	for {
+		select {
+		case s := <-ch:
+			out = append(out, s)
+		default:
+			return out
+		}
+	}
+}
+
+// StateChange is a transition recorded by a Recorder.
+type StateChange struct {
+	Breaker  string
+	From, To circuit.State
+}
+
+// Recorder records state changes. Pass its Record method to
+// circuit.WithOnStateChange. Unlike the StateChange channel, it never
+// drops a change. The zero value is ready to use, and it is safe for
+// concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	changes []StateChange
+}
+
+// Record records a state change. Its signature matches
+// circuit.WithOnStateChange.
+func (r *Recorder) Record(name string, from, to circuit.State) {
+	r.mu.Lock()
+	r.changes = append(r.changes, StateChange{Breaker: name, From: from, To: to})
+	r.mu.Unlock()
+}
+
+// Changes returns the recorded state changes, oldest first.
+func (r *Recorder) Changes() []StateChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]StateChange(nil), r.changes...)
+}
+
+// States returns the state entered by each recorded change, oldest first.
+func (r *Recorder) States() []circuit.State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]circuit.State, len(r.changes))
+	for i, c := range r.changes {
+		out[i] = c.To
+	}
+	return out
+}
+
+// Reset discards the recorded state changes.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.changes = nil
+	r.mu.Unlock()
+}
@@ -0,0 +1,223 @@
+package circuittest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/schigh/circuit"
+	"github.com/schigh/circuit/simulate"
+)
+
+func mustNewBreaker(t *testing.T, opts ...circuit.Option) *circuit.Breaker {
+	t.Helper()
+	b, err := circuit.NewBreaker(opts...)
+	if err != nil {
+		t.Fatalf("NewBreaker failed: %v", err)
+	}
+	return b
+}
+
+// fakeTB records failures instead of stopping the test.
+type fakeTB struct {
+	failed string
+}
+
+func (*fakeTB) Helper() {}
+
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.failed = fmt.Sprintf(format, args...)
+}
+
+func TestTripBreaker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("closed", func(t *testing.T) {
+		t.Parallel()
+		m := &Metrics{}
+		b := mustNewBreaker(t, circuit.WithName("trip"), circuit.WithThreshold(5), circuit.WithMetrics(m))
+		if err := TripBreaker(b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.State() != circuit.Open {
+			t.Fatal("expected the breaker to open")
+		}
+		if n := m.Count("trip", KindError); n != 6 {
+			t.Fatalf("expected 6 failures, got %d", n)
+		}
+		if err := TripBreaker(b); err != nil {
+			t.Fatalf("expected an open breaker to stay open, got %v", err)
+		}
+	})
+
+	t.Run("throttled", func(t *testing.T) {
+		t.Parallel()
+		clock := simulate.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		b := mustNewBreaker(t,
+			circuit.WithClock(clock),
+			circuit.WithThreshold(3),
+			circuit.WithWindow(time.Minute),
+			circuit.WithProbabilityFunc(func(float64) float64 { return 0.5 }),
+		)
+		if err := TripBreaker(b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		clock.Advance(2 * time.Minute)
+		if b.State() != circuit.Throttled {
+			t.Fatal("expected the breaker to throttle")
+		}
+		if err := TripBreaker(b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ignored errors", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, circuit.WithIsExcluded(func(err error) bool {
+			return errors.Is(err, ErrTripped)
+		}))
+		if err := TripBreaker(b); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestAssertEventuallyState(t *testing.T) {
+	t.Parallel()
+
+	t.Run("waits for lazy transitions", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, circuit.WithWindow(20*time.Millisecond))
+		if err := TripBreaker(b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		AssertEventuallyState(t, b, circuit.Throttled, time.Second)
+	})
+
+	t.Run("fails on timeout", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, circuit.WithName("stuck"))
+		f := &fakeTB{}
+		AssertEventuallyState(f, b, circuit.Open, 20*time.Millisecond)
+		if want := `breaker "stuck": expected state open within 20ms, got closed`; f.failed != want {
+			t.Fatalf("unexpected failure %q", f.failed)
+		}
+	})
+}
+
+func TestDrainStateChanges(t *testing.T) {
+	t.Parallel()
+
+	b := mustNewBreaker(t)
+	if got := DrainStateChanges(b.StateChange()); len(got) != 1 || got[0].State != circuit.Closed {
+		t.Fatalf("expected the initial state, got %v", got)
+	}
+	b.ForceOpen()
+	b.ForceClose()
+	got := DrainStateChanges(b.StateChange())
+	if len(got) != 2 || got[0].State != circuit.Open || got[1].State != circuit.Closed {
+		t.Fatalf("expected open then closed, got %v", got)
+	}
+	if got := DrainStateChanges(b.StateChange()); len(got) != 0 {
+		t.Fatalf("expected the channel to be drained, got %v", got)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	rec := &Recorder{}
+	b := mustNewBreaker(t, circuit.WithName("rec"), circuit.WithOnStateChange(rec.Record))
+	// more changes than the StateChange channel holds
+	for i := 0; i < 20; i++ {
+		b.ForceOpen()
+		b.ForceClose()
+	}
+	states := rec.States()
+	if len(states) != 40 || states[0] != circuit.Open || states[39] != circuit.Closed {
+		t.Fatalf("expected 40 changes, got %v", states)
+	}
+	if c := rec.Changes()[0]; c != (StateChange{Breaker: "rec", From: circuit.Closed, To: circuit.Open}) {
+		t.Fatalf("unexpected change %+v", c)
+	}
+	rec.Reset()
+	if len(rec.Changes()) != 0 {
+		t.Fatal("expected no changes after reset")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	excluded := errors.New("excluded")
+	m := &Metrics{}
+	b := mustNewBreaker(t,
+		circuit.WithName("metrics"),
+		circuit.WithMetrics(m),
+		circuit.WithTimeout(10*time.Millisecond),
+		circuit.WithThreshold(100),
+		circuit.WithIsExcluded(func(err error) bool { return errors.Is(err, excluded) }),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = circuit.Run(b, context.Background(), func(context.Context) (int, error) { return 0, nil })
+		}()
+	}
+	wg.Wait()
+	_, _ = circuit.Run(b, context.Background(), func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	_, _ = circuit.Run(b, context.Background(), func(context.Context) (int, error) { return 0, excluded })
+	b.ForceOpen()
+	_, _ = circuit.Run(b, context.Background(), func(context.Context) (int, error) { return 0, nil })
+
+	for kind, want := range map[Kind]int{
+		KindSuccess:     10,
+		KindError:       1,
+		KindTimeout:     1,
+		KindExcluded:    1,
+		KindStateChange: 1,
+		KindRejected:    1,
+	} {
+		if n := m.Count("metrics", kind); n != want {
+			t.Fatalf("expected %d %s events, got %d", want, kind, n)
+		}
+	}
+	if n := m.Count("other", KindSuccess); n != 0 {
+		t.Fatalf("expected no events for another breaker, got %d", n)
+	}
+
+	events := m.Events(KindStateChange, KindRejected)
+	if len(events) != 2 || events[0].To != circuit.Open || events[1].State != circuit.Open {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if e := m.Events(KindError)[0]; !errors.Is(e.Err, context.DeadlineExceeded) || e.Duration < 10*time.Millisecond {
+		t.Fatalf("unexpected error event %+v", e)
+	}
+	if len(m.Events()) != 15 {
+		t.Fatalf("expected 15 events, got %d", len(m.Events()))
+	}
+
+	m.Reset()
+	if len(m.Events()) != 0 {
+		t.Fatal("expected no events after reset")
+	}
+
+	type routeKey struct{}
+	lb := mustNewBreaker(t, circuit.WithMetrics(m), circuit.WithLabels(func(ctx context.Context) circuit.Labels {
+		return circuit.Labels{"route": ctx.Value(routeKey{}).(string)}
+	}))
+	ctx := context.WithValue(context.Background(), routeKey{}, "/users")
+	_, _ = circuit.Run(lb, ctx, func(context.Context) (int, error) { return 0, nil })
+	if e := m.Events(); len(e) != 1 || e[0].Labels["route"] != "/users" {
+		t.Fatalf("expected a labeled success, got %+v", e)
+	}
+}
@@ -0,0 +1,152 @@
+package circuittest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+// Kind identifies the MetricsCollector method an Event was recorded by.
+type Kind string
+
+// kinds of events recorded by Metrics
+const (
+	KindSuccess     Kind = "success"
+	KindError       Kind = "error"
+	KindTimeout     Kind = "timeout"
+	KindStateChange Kind = "state_change"
+	KindRejected    Kind = "rejected"
+	KindExcluded    Kind = "excluded"
+)
+
+// Event is a single call to a MetricsCollector method. Only the fields
+// the method receives are set.
+type Event struct {
+	Kind     Kind
+	Breaker  string
+	Duration time.Duration  // success and error
+	Err      error          // error and excluded
+	From, To circuit.State  // state change
+	State    circuit.State  // rejected
+	Labels   circuit.Labels // all but state change, if extracted with circuit.WithLabels
+}
+
+// Metrics is a circuit.MetricsCollector that records every call, for
+// assertions in tests. It also implements circuit.LabeledMetricsCollector,
+// recording the labels of each call. The zero value is ready to use, and
+// it is safe for concurrent use.
+type Metrics struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+var (
+	_ circuit.MetricsCollector        = (*Metrics)(nil)
+	_ circuit.LabeledMetricsCollector = (*Metrics)(nil)
+)
+
+func (m *Metrics) record(e Event) {
+	m.mu.Lock()
+	m.events = append(m.events, e)
+	m.mu.Unlock()
+}
+
+// RecordSuccess implements circuit.MetricsCollector.
+func (m *Metrics) RecordSuccess(name string, d time.Duration) {
+	m.record(Event{Kind: KindSuccess, Breaker: name, Duration: d})
+}
+
+// RecordError implements circuit.MetricsCollector.
+func (m *Metrics) RecordError(name string, d time.Duration, err error) {
+	m.record(Event{Kind: KindError, Breaker: name, Duration: d, Err: err})
+}
+
+// RecordTimeout implements circuit.MetricsCollector.
+func (m *Metrics) RecordTimeout(name string) {
+	m.record(Event{Kind: KindTimeout, Breaker: name})
+}
+
+// RecordStateChange implements circuit.MetricsCollector.
+func (m *Metrics) RecordStateChange(name string, from, to circuit.State) {
+	m.record(Event{Kind: KindStateChange, Breaker: name, From: from, To: to})
+}
+
+// RecordRejected implements circuit.MetricsCollector.
+func (m *Metrics) RecordRejected(name string, state circuit.State) {
+	m.record(Event{Kind: KindRejected, Breaker: name, State: state})
+}
+
+// RecordExcluded implements circuit.MetricsCollector.
+func (m *Metrics) RecordExcluded(name string, err error) {
+	m.record(Event{Kind: KindExcluded, Breaker: name, Err: err})
+}
+
+// RecordSuccessWithLabels implements circuit.LabeledMetricsCollector.
+func (m *Metrics) RecordSuccessWithLabels(name string, d time.Duration, labels circuit.Labels) {
+	m.record(Event{Kind: KindSuccess, Breaker: name, Duration: d, Labels: labels})
+}
+
+// RecordErrorWithLabels implements circuit.LabeledMetricsCollector.
+func (m *Metrics) RecordErrorWithLabels(name string, d time.Duration, err error, labels circuit.Labels) {
+	m.record(Event{Kind: KindError, Breaker: name, Duration: d, Err: err, Labels: labels})
+}
+
+// RecordTimeoutWithLabels implements circuit.LabeledMetricsCollector.
+func (m *Metrics) RecordTimeoutWithLabels(name string, labels circuit.Labels) {
+	m.record(Event{Kind: KindTimeout, Breaker: name, Labels: labels})
+}
+
+// RecordRejectedWithLabels implements circuit.LabeledMetricsCollector.
+func (m *Metrics) RecordRejectedWithLabels(name string, state circuit.State, labels circuit.Labels) {
+	m.record(Event{Kind: KindRejected, Breaker: name, State: state, Labels: labels})
+}
+
+// RecordExcludedWithLabels implements circuit.LabeledMetricsCollector.
+func (m *Metrics) RecordExcludedWithLabels(name string, err error, labels circuit.Labels) {
+	m.record(Event{Kind: KindExcluded, Breaker: name, Err: err, Labels: labels})
+}
+
+// Events returns the recorded events, oldest first. If kinds are given,
+// only events of those kinds are returned.
+func (m *Metrics) Events(kinds ...Kind) []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Event
+	for _, e := range m.events {
+		if len(kinds) == 0 || contains(kinds, e.Kind) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Count returns the number of events of kind recorded for the named
+// breaker, or for every breaker if name is empty.
+func (m *Metrics) Count(name string, kind Kind) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, e := range m.events {
+		if e.Kind == kind && (name == "" || e.Breaker == name) {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset discards the recorded events.
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	m.events = nil
+	m.mu.Unlock()
+}
+
+func contains(kinds []Kind, k Kind) bool {
+	for _, kind := range kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package circuit
+
+import "time"
+
+// Clock is a source of the current time. A Breaker uses the system clock
+// unless another is set with WithClock, e.g. to drive it through simulated
+// time. Run's timeout is always measured by the system clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// now returns the current time from the breaker's clock.
+func (b *Breaker) now() time.Time {
+	if b.clock != nil {
+		return b.clock.Now()
+	}
+	return time.Now()
+}
+
+// since returns the time elapsed since t by the breaker's clock.
+func (b *Breaker) since(t time.Time) time.Duration {
+	return b.now().Sub(t)
+}
@@ -0,0 +1,98 @@
+package circuit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+type latencyMetrics struct {
+	mockMetrics
+	latencies []time.Duration
+}
+
+func (m *latencyMetrics) RecordSuccess(_ string, d time.Duration) {
+	m.mu.Lock()
+	m.latencies = append(m.latencies, d)
+	m.mu.Unlock()
+}
+
+func TestWithClock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("drives transitions", func(t *testing.T) {
+		t.Parallel()
+		clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+		b := mustNewBreaker(t,
+			WithClock(clock),
+			WithWindow(time.Minute),
+			WithLockOut(10*time.Second),
+			WithBackOff(time.Minute),
+		)
+
+		done, err := b.Allow(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		clock.advance(time.Second)
+		done(context.DeadlineExceeded)
+		if b.State() != Open {
+			t.Fatal("expected breaker to open")
+		}
+		if s := b.Snapshot(); !s.Opened.Equal(clock.Now()) || !s.LockoutEnds.Equal(clock.Now().Add(10*time.Second)) {
+			t.Fatalf("expected timestamps from the clock, got %+v", s)
+		}
+
+		clock.advance(30 * time.Second)
+		if b.State() != Open {
+			t.Fatal("expected the error to stay in the window")
+		}
+		clock.advance(31 * time.Second)
+		if b.State() != Throttled {
+			t.Fatal("expected the error to leave the window")
+		}
+		clock.advance(time.Minute)
+		if b.State() != Closed {
+			t.Fatal("expected breaker to close after the backoff")
+		}
+
+		h := b.History()
+		if len(h) != 3 || !h[2].At.Equal(clock.Now()) {
+			t.Fatalf("expected transitions timed by the clock, got %+v", h)
+		}
+	})
+
+	t.Run("measures latency", func(t *testing.T) {
+		t.Parallel()
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		metrics := &latencyMetrics{}
+		b := mustNewBreaker(t, WithClock(clock), WithMetrics(metrics))
+		done, _ := b.Allow(context.Background())
+		clock.advance(250 * time.Millisecond)
+		done(nil)
+
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		if len(metrics.latencies) != 1 || metrics.latencies[0] != 250*time.Millisecond {
+			t.Fatalf("expected a latency of 250ms, got %v", metrics.latencies)
+		}
+	})
+}
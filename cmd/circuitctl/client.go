@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/schigh/circuit"
+)
+
+// client talks to a handler served by the admin package.
+type client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+func newClient(addr, token string) (*client, error) {
+	u, err := url.Parse(addr)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid address %q: expected a URL such as http://localhost:8080/admin/circuit", addr)
+	}
+	return &client{
+		base:  strings.TrimSuffix(addr, "/"),
+		token: token,
+		http:  http.DefaultClient,
+	}, nil
+}
+
+// list returns the state of every breaker.
+func (c *client) list(ctx context.Context) ([]circuit.BreakerState, error) {
+	var states []circuit.BreakerState
+	err := c.do(ctx, http.MethodGet, "/breakers", &states)
+	return states, err
+}
+
+// get returns the state of one breaker, including its config.
+func (c *client) get(ctx context.Context, name string) (circuit.BreakerState, error) {
+	var state circuit.BreakerState
+	err := c.do(ctx, http.MethodGet, "/breakers/"+escapeName(name), &state)
+	return state, err
+}
+
+// action runs force-open, force-close or reset on one breaker
+// and returns its resulting state.
+func (c *client) action(ctx context.Context, action, name string) (circuit.BreakerState, error) {
+	var state circuit.BreakerState
+	err := c.do(ctx, http.MethodPost, "/"+action+"/"+escapeName(name), &state)
+	return state, err
+}
+
+// watch calls fn with every state received from the event stream
+// until ctx is cancelled, the stream ends or fn returns an error.
+func (c *client) watch(ctx context.Context, fn func(circuit.BreakerState) error) error {
+	resp, err := c.send(ctx, http.MethodGet, "/events")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var state circuit.BreakerState
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return fmt.Errorf("invalid event: %w", err)
+		}
+		if err := fn(state); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+func (c *client) do(ctx context.Context, method, path string, v any) error {
+	resp, err := c.send(ctx, method, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// send performs a request and converts error responses to errors.
+func (c *client) send(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) != nil || body.Error == "" {
+		body.Error = http.StatusText(resp.StatusCode)
+	}
+	return nil, fmt.Errorf("%s %s: %s (%d)", method, path, body.Error, resp.StatusCode)
+}
+
+// escapeName escapes each segment of a breaker name, keeping
+// slashes so the name matches the handler's wildcard routes.
+func escapeName(name string) string {
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
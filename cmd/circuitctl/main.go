@@ -0,0 +1,184 @@
+// Command circuitctl inspects and controls the breakers of a running
+// service through the handler served by the admin package.
+//
+// Usage:
+//
+//	circuitctl [flags] <command> [args]
+//
+// Commands:
+//
+//	list                 list every breaker
+//	get <name>           show one breaker and its config
+//	watch [name...]      stream state changes, optionally for some breakers only
+//	history <name>       show a breaker's recent transitions
+//	force-open <name>    pin a breaker open
+//	force-close <name>   pin a breaker closed
+//	reset <name>         unpin a breaker, clear its errors and close it
+//
+// Flags:
+//
+//	-addr    URL the admin handler is mounted at ($CIRCUITCTL_ADDR)
+//	-token   bearer token for force-open, force-close and reset ($CIRCUITCTL_TOKEN)
+//	-o       output format: table or json (default table)
+//	-color   colourise states: auto, always or never (default auto)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+const defaultAddr = "http://localhost:8080"
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes a command and returns the process exit code:
+// 0 on success, 1 if the command failed and 2 for usage errors.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("circuitctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", envOr("CIRCUITCTL_ADDR", defaultAddr), "URL the admin handler is mounted at")
+	token := fs.String("token", os.Getenv("CIRCUITCTL_TOKEN"), "bearer token for force-open, force-close and reset")
+	format := fs.String("o", "table", "output format: table or json")
+	colour := fs.String("color", "auto", "colourise states: auto, always or never")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: circuitctl [flags] <list|get|watch|history|force-open|force-close|reset> [args]")
+		fs.PrintDefaults()
+	}
+
+	// flags are accepted before and directly after the command
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return 2
+	}
+	cmdArgs := fs.Args()
+
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(stderr, "circuitctl: unknown output format %q\n", *format)
+		return 2
+	}
+	p := &printer{w: stdout, json: *format == "json", now: time.Now}
+	switch *colour {
+	case "auto":
+		p.colour = isTerminal(stdout) && os.Getenv("NO_COLOR") == ""
+	case "always":
+		p.colour = true
+	case "never":
+	default:
+		fmt.Fprintf(stderr, "circuitctl: unknown color mode %q\n", *colour)
+		return 2
+	}
+
+	c, err := newClient(*addr, *token)
+	if err != nil {
+		fmt.Fprintf(stderr, "circuitctl: %v\n", err)
+		return 2
+	}
+
+	err = dispatch(ctx, c, p, cmd, cmdArgs)
+	var usage usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage):
+		fmt.Fprintf(stderr, "circuitctl: %v\n", err)
+		fs.Usage()
+		return 2
+	default:
+		fmt.Fprintf(stderr, "circuitctl: %v\n", err)
+		return 1
+	}
+}
+
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+func dispatch(ctx context.Context, c *client, p *printer, cmd string, args []string) error {
+	switch cmd {
+	case "list":
+		if len(args) != 0 {
+			return usageError("list takes no arguments")
+		}
+		states, err := c.list(ctx)
+		if err != nil {
+			return err
+		}
+		return p.states(states)
+
+	case "get", "history":
+		if len(args) != 1 {
+			return usageError(cmd + " takes exactly one breaker name")
+		}
+		state, err := c.get(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if cmd == "history" {
+			return p.history(state.History)
+		}
+		return p.state(state)
+
+	case "watch":
+		names := make(map[string]bool, len(args))
+		for _, name := range args {
+			names[name] = true
+		}
+		return c.watch(ctx, func(state circuit.BreakerState) error {
+			if len(names) > 0 && !names[state.Name] {
+				return nil
+			}
+			return p.event(state)
+		})
+
+	case "force-open", "force-close", "reset":
+		if len(args) != 1 {
+			return usageError(cmd + " takes exactly one breaker name")
+		}
+		state, err := c.action(ctx, cmd, args[0])
+		if err != nil {
+			return err
+		}
+		if p.json {
+			return p.writeJSON(state, true)
+		}
+		return p.states([]circuit.BreakerState{state})
+	}
+	return usageError(fmt.Sprintf("unknown command %q", cmd))
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// isTerminal reports whether w is a character device, such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/schigh/circuit"
+	"github.com/schigh/circuit/admin"
+)
+
+const token = "s3cret"
+
+// newServer starts an admin handler for a box with three breakers.
+func newServer(t *testing.T) (*circuit.BreakerBox, string) {
+	t.Helper()
+	box := circuit.NewBreakerBox()
+	for _, name := range []string{"users", "users/get", "orders"} {
+		if _, err := box.Create(circuit.WithName(name), circuit.WithLockOut(time.Minute), circuit.WithStats(time.Minute)); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	srv := httptest.NewServer(admin.NewHandler(box, admin.WithBearerToken(token)))
+	t.Cleanup(srv.Close)
+	return box, srv.URL
+}
+
+// syncBuffer is a bytes.Buffer that is safe to read while a command writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func runCmd(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("list table", func(t *testing.T) {
+		t.Parallel()
+		box, addr := newServer(t)
+		box.Load("orders").ForceOpen()
+
+		code, out, errOut := runCmd(t, "-addr", addr, "list")
+		if code != 0 {
+			t.Fatalf("expected exit 0, got %d: %s", code, errOut)
+		}
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("expected header and 3 rows, got:\n%s", out)
+		}
+		if !strings.HasPrefix(lines[0], "NAME") || !strings.Contains(lines[0], "STATE") {
+			t.Fatalf("unexpected header: %q", lines[0])
+		}
+		if !strings.HasPrefix(lines[1], "orders") || !strings.Contains(lines[1], "open") || !strings.Contains(lines[1], "forced") {
+			t.Fatalf("unexpected row: %q", lines[1])
+		}
+		if !strings.HasPrefix(lines[3], "users/get") || !strings.Contains(lines[3], "closed") {
+			t.Fatalf("unexpected row: %q", lines[3])
+		}
+		if strings.Contains(out, "\x1b[") {
+			t.Fatal("expected no colour when not writing to a terminal")
+		}
+		// columns are aligned
+		if strings.Index(lines[1], "open") != strings.Index(lines[0], "STATE") {
+			t.Fatalf("expected aligned columns:\n%s", out)
+		}
+	})
+
+	t.Run("list colour", func(t *testing.T) {
+		t.Parallel()
+		box, addr := newServer(t)
+		box.Load("orders").ForceOpen()
+
+		_, out, _ := runCmd(t, "-addr", addr, "-color", "always", "list")
+		if !strings.Contains(out, ansiRed+"open") || !strings.Contains(out, ansiGreen+"closed") {
+			t.Fatalf("expected coloured states, got %q", out)
+		}
+	})
+
+	t.Run("list json", func(t *testing.T) {
+		t.Parallel()
+		_, addr := newServer(t)
+
+		code, out, _ := runCmd(t, "-addr", addr, "list", "-o", "json")
+		if code != 0 {
+			t.Fatalf("expected exit 0, got %d", code)
+		}
+		var states []circuit.BreakerState
+		if err := json.Unmarshal([]byte(out), &states); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, out)
+		}
+		if len(states) != 3 || states[0].Name != "orders" || states[0].ErrorCount == nil {
+			t.Fatalf("unexpected states: %+v", states)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		t.Parallel()
+		_, addr := newServer(t)
+
+		code, out, _ := runCmd(t, "-addr", addr, "get", "users/get")
+		if code != 0 {
+			t.Fatalf("expected exit 0, got %d", code)
+		}
+		for _, want := range []string{"users/get", "closed", "LockOut:", "1m0s", "Error rate:", "0 rejected"} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("expected %q in output:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("get unknown breaker", func(t *testing.T) {
+		t.Parallel()
+		_, addr := newServer(t)
+
+		code, _, errOut := runCmd(t, "-addr", addr, "get", "nope")
+		if code != 1 || !strings.Contains(errOut, `breaker "nope" not found`) || !strings.Contains(errOut, "404") {
+			t.Fatalf("expected not found error, got %d: %s", code, errOut)
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		t.Parallel()
+		box, addr := newServer(t)
+		b := box.Load("users")
+		done, _ := b.Allow(context.Background())
+		done(context.DeadlineExceeded)
+		b.State() // closed -> open
+		b.Reset()
+
+		code, out, _ := runCmd(t, "-addr", addr, "history", "users")
+		if code != 0 {
+			t.Fatalf("expected exit 0, got %d", code)
+		}
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "TIME") {
+			t.Fatalf("expected header and 2 transitions, got:\n%s", out)
+		}
+		if !strings.Contains(lines[1], "error threshold exceeded") || !strings.Contains(lines[2], "reset") {
+			t.Fatalf("unexpected history:\n%s", out)
+		}
+
+		_, out, _ = runCmd(t, "-addr", addr, "-o", "json", "history", "users")
+		var transitions []circuit.Transition
+		if err := json.Unmarshal([]byte(out), &transitions); err != nil || len(transitions) != 2 {
+			t.Fatalf("unexpected JSON history: %v\n%s", err, out)
+		}
+		if transitions[0].From != circuit.Closed || transitions[0].To != circuit.Open || transitions[0].Errors != 1 {
+			t.Fatalf("unexpected transition: %+v", transitions[0])
+		}
+
+		_, out, _ = runCmd(t, "-addr", addr, "history", "orders")
+		if strings.TrimSpace(out) != "no transitions recorded" {
+			t.Fatalf("unexpected empty history: %q", out)
+		}
+	})
+
+	t.Run("actions", func(t *testing.T) {
+		t.Parallel()
+		box, addr := newServer(t)
+		b := box.Load("users/get")
+
+		code, out, errOut := runCmd(t, "-addr", addr, "-token", token, "force-open", "users/get")
+		if code != 0 || !strings.Contains(out, "open") || !strings.Contains(out, "forced") {
+			t.Fatalf("unexpected force-open result %d: %s%s", code, out, errOut)
+		}
+		if b.State() != circuit.Open {
+			t.Fatalf("expected breaker to be open, got %s", b.State())
+		}
+
+		runCmd(t, "-addr", addr, "-token", token, "force-close", "users/get")
+		if b.State() != circuit.Closed || !b.Snapshot().Forced {
+			t.Fatal("expected breaker to be forced closed")
+		}
+
+		code, out, _ = runCmd(t, "-addr", addr, "-token", token, "-o", "json", "reset", "users/get")
+		var state circuit.BreakerState
+		if code != 0 || json.Unmarshal([]byte(out), &state) != nil || state.Forced {
+			t.Fatalf("unexpected reset result %d: %s", code, out)
+		}
+	})
+
+	t.Run("actions require token", func(t *testing.T) {
+		t.Parallel()
+		box, addr := newServer(t)
+
+		code, _, errOut := runCmd(t, "-addr", addr, "-token", "wrong", "force-open", "users")
+		if code != 1 || !strings.Contains(errOut, "401") {
+			t.Fatalf("expected unauthorized error, got %d: %s", code, errOut)
+		}
+		if box.Load("users").State() != circuit.Closed {
+			t.Fatal("expected breaker to be unchanged")
+		}
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		t.Parallel()
+		for _, args := range [][]string{
+			{},
+			{"frobnicate"},
+			{"get"},
+			{"list", "extra"},
+			{"-o", "yaml", "list"},
+			{"-color", "sometimes", "list"},
+			{"-addr", "not a url", "list"},
+			{"-bogus", "list"},
+		} {
+			if code, _, _ := runCmd(t, args...); code != 2 {
+				t.Fatalf("expected exit 2 for %v, got %d", args, code)
+			}
+		}
+	})
+}
+
+func TestRunWatch(t *testing.T) {
+	t.Parallel()
+	box, addr := newServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout syncBuffer
+	var stderr bytes.Buffer
+	exited := make(chan int)
+	go func() {
+		exited <- run(ctx, []string{"-addr", addr, "-o", "json", "watch", "users"}, &stdout, &stderr)
+	}()
+
+	// wait for the initial state, then trigger a change
+	waitFor := func(substr string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for !strings.Contains(stdout.String(), substr) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %q, got:\n%s", substr, stdout.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor(`"state":"closed"`)
+	box.Load("orders").ForceOpen()
+	box.Load("users").ForceOpen()
+	waitFor(`"state":"open"`)
+
+	cancel()
+	if code := <-exited; code != 0 {
+		t.Fatalf("expected exit 0 after cancel, got %d: %s", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected only events for users, got:\n%s", stdout.String())
+	}
+	for _, line := range lines {
+		var state circuit.BreakerState
+		if err := json.Unmarshal([]byte(line), &state); err != nil || state.Name != "users" {
+			t.Fatalf("unexpected event %q: %v", line, err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// printer writes breaker states as tables or JSON.
+type printer struct {
+	w      io.Writer
+	json   bool
+	colour bool
+	now    func() time.Time
+}
+
+// states prints a list of breakers.
+func (p *printer) states(states []circuit.BreakerState) error {
+	if p.json {
+		return p.writeJSON(states, true)
+	}
+	rows := make([][]string, 0, len(states))
+	for _, s := range states {
+		rows = append(rows, []string{s.Name, s.State.String(), errorCount(s), p.since(s), p.detail(s)})
+	}
+	p.table([]string{"NAME", "STATE", "ERRORS", "SINCE", "DETAIL"}, rows, 1)
+	return nil
+}
+
+// state prints a single breaker, including its config if present.
+func (p *printer) state(s circuit.BreakerState) error {
+	if p.json {
+		return p.writeJSON(s, true)
+	}
+	rows := [][]string{
+		{"Name:", s.Name},
+		{"State:", s.State.String()},
+		{"Errors:", errorCount(s)},
+		{"Since:", p.since(s)},
+	}
+	if d := p.detail(s); d != "" {
+		rows = append(rows, []string{"Detail:", d})
+	}
+	if c := s.Config; c != nil {
+		rows = append(rows,
+			[]string{"Threshold:", fmt.Sprint(c.Threshold)},
+			[]string{"Window:", time.Duration(c.Window).String()},
+			[]string{"Timeout:", time.Duration(c.Timeout).String()},
+			[]string{"BackOff:", time.Duration(c.BackOff).String()},
+			[]string{"LockOut:", time.Duration(c.LockOut).String()},
+		)
+		if c.Estimation != "" {
+			estimation := c.Estimation
+			if c.Smooth {
+				estimation += " (smooth)"
+			}
+			rows = append(rows, []string{"Estimation:", estimation})
+		}
+		if c.Parent != "" {
+			rows = append(rows, []string{"Parent:", c.Parent})
+		}
+	}
+	if st := s.Stats; st != nil {
+		rows = append(rows,
+			[]string{"Requests:", fmt.Sprintf("%.1f/s over %s", st.RequestRate, time.Duration(st.Window))},
+			[]string{"Outcomes:", fmt.Sprintf("%d succeeded, %d failed (%d timed out), %d rejected, %d excluded",
+				st.Successes, st.Failures, st.Timeouts, st.Rejected, st.Excluded)},
+			[]string{"Error rate:", fmt.Sprintf("%.1f%%", st.ErrorRate*100)},
+			[]string{"Latency:", fmt.Sprintf("p50 %s, p90 %s, p99 %s",
+				time.Duration(st.Latency.P50), time.Duration(st.Latency.P90), time.Duration(st.Latency.P99))},
+		)
+	}
+	p.table(nil, rows, -1)
+	return nil
+}
+
+// event prints one state received while watching.
+func (p *printer) event(s circuit.BreakerState) error {
+	if p.json {
+		return p.writeJSON(s, false)
+	}
+	line := fmt.Sprintf("%s  %s  %s", p.now().Format(time.TimeOnly), s.Name, p.paint(s.State, s.State.String()))
+	if d := p.detail(s); d != "" {
+		line += "  (" + d + ")"
+	}
+	_, err := fmt.Fprintln(p.w, line)
+	return err
+}
+
+// history prints a breaker's recent transitions, oldest first.
+func (p *printer) history(transitions []circuit.Transition) error {
+	if p.json {
+		if transitions == nil {
+			transitions = []circuit.Transition{}
+		}
+		return p.writeJSON(transitions, true)
+	}
+	if len(transitions) == 0 {
+		_, err := fmt.Fprintln(p.w, "no transitions recorded")
+		return err
+	}
+
+	rows := make([][]string, 0, len(transitions))
+	for _, t := range transitions {
+		rows = append(rows, []string{
+			t.At.Format(time.RFC3339),
+			t.From.String(),
+			t.To.String(),
+			fmt.Sprint(t.Errors),
+			t.Reason,
+		})
+	}
+	p.table([]string{"TIME", "FROM", "TO", "ERRORS", "REASON"}, rows, 2)
+	return nil
+}
+
+// since describes how long the breaker has been in its current state.
+func (p *printer) since(s circuit.BreakerState) string {
+	var at *time.Time
+	switch s.State {
+	case circuit.Closed:
+		at = s.ClosedSince
+	case circuit.Throttled:
+		at = s.Throttled
+	case circuit.Open:
+		at = s.Opened
+	}
+	if at == nil {
+		return "-"
+	}
+	return p.now().Sub(*at).Round(time.Second).String()
+}
+
+// detail describes pins, flapping, pending lockout or backoff and ramp-up admission.
+func (p *printer) detail(s circuit.BreakerState) string {
+	var parts []string
+	if s.Forced {
+		parts = append(parts, "forced")
+	}
+	if s.Flapping {
+		parts = append(parts, "flapping")
+	}
+	if s.LockoutEnds != nil && s.LockoutEnds.After(p.now()) {
+		parts = append(parts, "lockout ends "+p.relative(*s.LockoutEnds))
+	}
+	if s.BackOffEnds != nil && s.BackOffEnds.After(p.now()) {
+		parts = append(parts, "backoff ends "+p.relative(*s.BackOffEnds))
+	}
+	if s.Admission > 0 {
+		parts = append(parts, fmt.Sprintf("admitting %d%%", s.Admission))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p *printer) relative(t time.Time) string {
+	d := t.Sub(p.now()).Round(time.Second)
+	if d < 0 {
+		return (-d).String() + " ago"
+	}
+	return "in " + d.String()
+}
+
+// table writes rows in aligned columns. Cells in stateCol are coloured
+// by state; pass -1 to disable colouring.
+func (p *printer) table(header []string, rows [][]string, stateCol int) {
+	all := rows
+	if header != nil {
+		all = append([][]string{header}, rows...)
+	}
+	var widths []int
+	for _, row := range all {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	for r, row := range all {
+		var b strings.Builder
+		for i, cell := range row {
+			padded := cell
+			if i < len(row)-1 {
+				padded += strings.Repeat(" ", widths[i]-len(cell)+2)
+			}
+			switch {
+			case header != nil && r == 0 && p.colour:
+				padded = ansiBold + padded + ansiReset
+			case i == stateCol:
+				var state circuit.State
+				if state.UnmarshalJSON([]byte(`"`+cell+`"`)) == nil {
+					padded = p.paint(state, padded)
+				}
+			}
+			b.WriteString(padded)
+		}
+		fmt.Fprintln(p.w, strings.TrimRight(b.String(), " "))
+	}
+}
+
+// paint colours text by state, if colour is enabled.
+func (p *printer) paint(state circuit.State, text string) string {
+	if !p.colour {
+		return text
+	}
+	switch state {
+	case circuit.Closed:
+		return ansiGreen + text + ansiReset
+	case circuit.Throttled:
+		return ansiYellow + text + ansiReset
+	case circuit.Open:
+		return ansiRed + text + ansiReset
+	}
+	return text
+}
+
+func (p *printer) writeJSON(v any, indent bool) error {
+	enc := json.NewEncoder(p.w)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+func errorCount(s circuit.BreakerState) string {
+	if s.ErrorCount == nil {
+		return "-"
+	}
+	return fmt.Sprint(*s.ErrorCount)
+}
@@ -0,0 +1,537 @@
+package circuit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Duration is a time.Duration that is written and read as a string
+// such as "30s" or "5m". It implements encoding.TextMarshaler and
+// encoding.TextUnmarshaler, so it works with encoding/json as well
+// as most YAML and TOML libraries.
+type Duration time.Duration
+
+// MarshalText encodes the duration in time.Duration string format.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText decodes a duration in time.ParseDuration format.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("circuit: invalid duration %q: %w", string(text), err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// BreakerConfig declares the settings of a single Breaker.
+// Zero values fall back to the same defaults as NewBreaker.
+// Options that take code (metrics, hooks, error classifiers)
+// cannot be declared and must be supplied programmatically.
+// If Smooth is set, Estimation names a ProbabilityFunc rather than an
+// EstimationFunc, and defaults to "linear".
+type BreakerConfig struct {
+	Name                  string   `json:"name" yaml:"name" toml:"name"`
+	Parent                string   `json:"parent,omitempty" yaml:"parent,omitempty" toml:"parent,omitempty"`
+	Timeout               Duration `json:"timeout,omitempty" yaml:"timeout,omitempty" toml:"timeout,omitempty"`
+	BackOff               Duration `json:"backoff,omitempty" yaml:"backoff,omitempty" toml:"backoff,omitempty"`
+	Window                Duration `json:"window,omitempty" yaml:"window,omitempty" toml:"window,omitempty"`
+	Threshold             uint32   `json:"threshold,omitempty" yaml:"threshold,omitempty" toml:"threshold,omitempty"`
+	LockOut               Duration `json:"lockout,omitempty" yaml:"lockout,omitempty" toml:"lockout,omitempty"`
+	Estimation            string   `json:"estimation,omitempty" yaml:"estimation,omitempty" toml:"estimation,omitempty"`
+	Smooth                bool     `json:"smooth,omitempty" yaml:"smooth,omitempty" toml:"smooth,omitempty"`
+	OpeningResetsErrors   bool     `json:"opening_resets_errors,omitempty" yaml:"opening_resets_errors,omitempty" toml:"opening_resets_errors,omitempty"`
+	DeterministicThrottle bool     `json:"deterministic_throttle,omitempty" yaml:"deterministic_throttle,omitempty" toml:"deterministic_throttle,omitempty"`
+	Strict                bool     `json:"strict,omitempty" yaml:"strict,omitempty" toml:"strict,omitempty"`
+	StatsWindow           Duration `json:"stats_window,omitempty" yaml:"stats_window,omitempty" toml:"stats_window,omitempty"`
+}
+
+// BoxConfig declares the breakers held by a BreakerBox.
+type BoxConfig struct {
+	Breakers []BreakerConfig `json:"breakers" yaml:"breakers" toml:"breakers"`
+}
+
+// ConfigError describes an invalid value in a configuration document.
+type ConfigError struct {
+	Breaker string // Name of the offending breaker, if known
+	Field   string // Name of the offending field
+	Reason  string // Human-readable explanation
+}
+
+func (e ConfigError) Error() string {
+	if e.Breaker == "" {
+		return fmt.Sprintf("circuit: invalid config: %s: %s", e.Field, e.Reason)
+	}
+	return fmt.Sprintf("circuit: invalid config for breaker %q: %s: %s", e.Breaker, e.Field, e.Reason)
+}
+
+// namedFunc pairs a built-in function with its name.
+type namedFunc[F EstimationFunc | ProbabilityFunc] struct {
+	name string
+	f    F
+}
+
+var (
+	builtinEstimations = []namedFunc[EstimationFunc]{
+		{"linear", Linear},
+		{"logarithmic", Logarithmic},
+		{"exponential", Exponential},
+		{"ease-in-out", EaseInOut},
+		{"jittered-linear", JitteredLinear},
+	}
+	builtinProbabilities = []namedFunc[ProbabilityFunc]{
+		{"linear", LinearProbability},
+		{"logarithmic", LogarithmicProbability},
+		{"exponential", ExponentialProbability},
+		{"ease-in-out", EaseInOutProbability},
+		{"jittered-linear", JitteredLinearProbability},
+	}
+)
+
+var (
+	estimationMu     sync.RWMutex
+	estimationFuncs  = registry(builtinEstimations)
+	probabilityFuncs = registry(builtinProbabilities)
+)
+
+// registry builds a name lookup from the built-in functions.
+func registry[F EstimationFunc | ProbabilityFunc](builtins []namedFunc[F]) map[string]F {
+	m := make(map[string]F, len(builtins))
+	for _, nf := range builtins {
+		m[nf.name] = nf.f
+	}
+	return m
+}
+
+// isFunc reports whether f is the package-level function g. Each
+// package-level function has its own code pointer, unlike closures,
+// which share one per function literal, so g must not be a closure.
+func isFunc[F EstimationFunc | ProbabilityFunc](f, g F) bool {
+	return reflect.ValueOf(f).Pointer() == reflect.ValueOf(g).Pointer()
+}
+
+// builtinName returns the name of f if it is one of builtins,
+// or the empty string otherwise.
+func builtinName[F EstimationFunc | ProbabilityFunc](builtins []namedFunc[F], f F) string {
+	if f == nil {
+		return ""
+	}
+	for _, nf := range builtins {
+		if isFunc(f, nf.f) {
+			return nf.name
+		}
+	}
+	return ""
+}
+
+// RegisterEstimationFunc makes a custom EstimationFunc available to
+// configuration documents under the given name. Registering an
+// existing name replaces it.
+func RegisterEstimationFunc(name string, f EstimationFunc) {
+	estimationMu.Lock()
+	estimationFuncs[name] = f
+	estimationMu.Unlock()
+}
+
+// EstimationFuncByName returns the EstimationFunc registered under name.
+// The built-in names are "linear", "logarithmic", "exponential",
+// "ease-in-out" and "jittered-linear".
+func EstimationFuncByName(name string) (EstimationFunc, bool) {
+	estimationMu.RLock()
+	f, ok := estimationFuncs[name]
+	estimationMu.RUnlock()
+	return f, ok
+}
+
+// RegisterProbabilityFunc makes a custom ProbabilityFunc available to
+// configuration documents that set Smooth, under the given name.
+// Registering an existing name replaces it.
+func RegisterProbabilityFunc(name string, f ProbabilityFunc) {
+	estimationMu.Lock()
+	probabilityFuncs[name] = f
+	estimationMu.Unlock()
+}
+
+// ProbabilityFuncByName returns the ProbabilityFunc registered under name.
+// The built-in names are the same as for EstimationFuncByName. If only an
+// EstimationFunc is registered under name, it is returned interpolated.
+func ProbabilityFuncByName(name string) (ProbabilityFunc, bool) {
+	estimationMu.RLock()
+	f, ok := probabilityFuncs[name]
+	estimationMu.RUnlock()
+	if ok {
+		return f, true
+	}
+	if g, ok := EstimationFuncByName(name); ok {
+		return Interpolate(g), true
+	}
+	return nil, false
+}
+
+// CustomEstimation is the estimation name reported by Breaker.Config
+// for an EstimationFunc or ProbabilityFunc that was neither chosen from
+// a configuration document nor one of the built-in functions.
+const CustomEstimation = "custom"
+
+// estimationFuncNames returns the registered names in sorted order.
+// If smooth is set, names registered as a ProbabilityFunc are included.
+func estimationFuncNames(smooth bool) []string {
+	estimationMu.RLock()
+	names := make([]string, 0, len(estimationFuncs))
+	for name := range estimationFuncs {
+		names = append(names, name)
+	}
+	if smooth {
+		for name := range probabilityFuncs {
+			if _, dup := estimationFuncs[name]; !dup {
+				names = append(names, name)
+			}
+		}
+	}
+	estimationMu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the breaker config for invalid values.
+// All problems are reported, joined into a single error.
+func (c BreakerConfig) Validate() error {
+	var errs []error
+	invalid := func(field, reason string) {
+		errs = append(errs, ConfigError{Breaker: c.Name, Field: field, Reason: reason})
+	}
+
+	if c.Name == "" {
+		invalid("name", "must not be empty")
+	}
+	if c.Parent != "" && c.Parent == c.Name {
+		invalid("parent", "a breaker cannot be its own parent")
+	}
+	for _, d := range []struct {
+		field string
+		value Duration
+	}{
+		{"timeout", c.Timeout},
+		{"backoff", c.BackOff},
+		{"window", c.Window},
+		{"lockout", c.LockOut},
+		{"stats_window", c.StatsWindow},
+	} {
+		if d.value < 0 {
+			invalid(d.field, fmt.Sprintf("must not be negative, got %s", time.Duration(d.value)))
+		}
+	}
+	if c.Estimation != "" {
+		known := false
+		if c.Smooth {
+			_, known = ProbabilityFuncByName(c.Estimation)
+		} else {
+			_, known = EstimationFuncByName(c.Estimation)
+		}
+		if !known {
+			invalid("estimation", fmt.Sprintf("unknown estimation function %q (known: %v)", c.Estimation, estimationFuncNames(c.Smooth)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Options converts the config into functional options.
+// Only fields with non-zero values produce an option.
+// The parent relationship is not included, since it must be
+// resolved against other breakers; see NewBreakerBoxFromConfig.
+func (c BreakerConfig) Options() ([]Option, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := []Option{WithName(c.Name)}
+	if c.Timeout != 0 {
+		opts = append(opts, WithTimeout(time.Duration(c.Timeout)))
+	}
+	if c.BackOff != 0 {
+		opts = append(opts, WithBackOff(time.Duration(c.BackOff)))
+	}
+	if c.Window != 0 {
+		opts = append(opts, WithWindow(time.Duration(c.Window)))
+	}
+	if c.Threshold != 0 {
+		opts = append(opts, WithThreshold(c.Threshold))
+	}
+	if c.LockOut != 0 {
+		opts = append(opts, WithLockOut(time.Duration(c.LockOut)))
+	}
+	if c.OpeningResetsErrors {
+		opts = append(opts, WithOpeningResetsErrors(true))
+	}
+	if c.DeterministicThrottle {
+		opts = append(opts, WithDeterministicThrottle(true))
+	}
+	if c.Strict {
+		opts = append(opts, WithStrict(true))
+	}
+	if c.StatsWindow != 0 {
+		opts = append(opts, WithStats(time.Duration(c.StatsWindow)))
+	}
+	switch {
+	case c.Smooth:
+		name := c.Estimation
+		if name == "" {
+			name = "linear"
+		}
+		f, _ := ProbabilityFuncByName(name)
+		opts = append(opts, withProbability(name, f))
+	case c.Estimation != "":
+		f, _ := EstimationFuncByName(c.Estimation)
+		opts = append(opts, withEstimation(c.Estimation, f))
+	}
+	return opts, nil
+}
+
+// Validate checks every breaker config, and also checks for duplicate
+// names, unknown parents and parent cycles.
+func (c BoxConfig) Validate() error {
+	var errs []error
+	byName := make(map[string]BreakerConfig, len(c.Breakers))
+	for i, bc := range c.Breakers {
+		if err := bc.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if bc.Name == "" {
+			continue
+		}
+		if _, dup := byName[bc.Name]; dup {
+			errs = append(errs, ConfigError{
+				Breaker: bc.Name,
+				Field:   fmt.Sprintf("breakers[%d].name", i),
+				Reason:  "duplicate breaker name",
+			})
+			continue
+		}
+		byName[bc.Name] = bc
+	}
+
+	for _, bc := range c.Breakers {
+		if bc.Parent == "" || bc.Parent == bc.Name {
+			continue
+		}
+		if _, ok := byName[bc.Parent]; !ok {
+			errs = append(errs, ConfigError{
+				Breaker: bc.Name,
+				Field:   "parent",
+				Reason:  fmt.Sprintf("unknown parent %q", bc.Parent),
+			})
+			continue
+		}
+		// walk up the chain looking for this breaker; the step bound
+		// stops walks that enter a cycle this breaker is not part of
+		p := bc.Parent
+		for steps := 0; p != "" && steps <= len(byName); steps++ {
+			if p == bc.Name {
+				errs = append(errs, ConfigError{
+					Breaker: bc.Name,
+					Field:   "parent",
+					Reason:  "parent chain forms a cycle",
+				})
+				break
+			}
+			p = byName[p].Parent
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseConfig decodes and validates a configuration document.
+// The unmarshal function determines the document format, for example
+// UnmarshalStrictJSON, or yaml.Unmarshal from a YAML library of your
+// choice. Whether unknown keys are reported depends on that function;
+// json.Unmarshal, for one, silently drops them.
+func ParseConfig(data []byte, unmarshal func([]byte, any) error) (BoxConfig, error) {
+	var cfg BoxConfig
+	if err := unmarshal(data, &cfg); err != nil {
+		return BoxConfig{}, fmt.Errorf("circuit: unable to decode config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return BoxConfig{}, err
+	}
+	return cfg, nil
+}
+
+// UnmarshalStrictJSON is like json.Unmarshal, but reports unknown keys,
+// such as a misspelt setting, and trailing data as errors. It is meant
+// to be passed to ParseConfig.
+func UnmarshalStrictJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("json: unexpected data after the document")
+	}
+	return nil
+}
+
+// NewBreakerBoxFromConfig returns a BreakerBox populated with the breakers
+// declared in cfg. Parents are created before their children regardless
+// of declaration order. The supplied options are applied to every breaker
+// before its declared settings, which is useful for options that cannot
+// be declared, such as WithMetrics.
+func NewBreakerBoxFromConfig(cfg BoxConfig, opts ...Option) (*BreakerBox, error) {
+	bb := NewBreakerBox()
+	if err := bb.ApplyConfig(cfg, opts...); err != nil {
+		return nil, err
+	}
+	return bb, nil
+}
+
+// ApplyConfig makes the box match a (re)loaded configuration document.
+// Declared breakers that already exist are reconfigured in place via
+// Reconfigure, keeping their state; any setting missing from the document
+// reverts to its default, or to the value given in opts. Declared breakers
+// that do not exist are created, and breakers that are not declared are
+// left untouched. The parent of an existing breaker cannot be changed, so
+// a document that declares a different one is rejected with a ConfigError.
+// The document, and the options it results in for every breaker, are
+// validated before any breaker is modified.
+func (bb *BreakerBox) ApplyConfig(cfg BoxConfig, opts ...Option) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	// build and check the options for every breaker before changing any
+	type change struct {
+		bc       BreakerConfig
+		existing *Breaker
+		opts     []Option
+	}
+	changes := make([]change, 0, len(cfg.Breakers))
+	var errs []error
+	for _, bc := range cfg.Breakers {
+		declared, err := bc.Options()
+		if err != nil {
+			return err
+		}
+
+		c := change{bc: bc, existing: bb.Load(bc.Name)}
+		if c.existing != nil {
+			if err := parentChange(c.existing, bc); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			c.opts = append(append(resetOptions(), opts...), declared...)
+			c.existing.stateMX.Lock()
+			_, err = c.existing.reconfigured(c.opts...)
+			c.existing.stateMX.Unlock()
+		} else {
+			c.opts = append(append([]Option{}, opts...), declared...)
+			err = validateOptions(c.opts...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("circuit: breaker %q: %w", bc.Name, err))
+		}
+		changes = append(changes, c)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	pending := changes
+	for len(pending) > 0 {
+		var deferred []change
+		for _, c := range pending {
+			if c.existing != nil {
+				if err := c.existing.Reconfigure(c.opts...); err != nil {
+					return err
+				}
+				continue
+			}
+
+			all := c.opts
+			if c.bc.Parent != "" {
+				parent := bb.Load(c.bc.Parent)
+				if parent == nil {
+					deferred = append(deferred, c)
+					continue
+				}
+				all = append(all, WithParent(parent))
+			}
+
+			// hold createMu so a concurrent LoadOrCreate cannot have its
+			// breaker replaced; reconfigure a breaker created since the check
+			bb.createMu.Lock()
+			existing := bb.Load(c.bc.Name)
+			var err error
+			if existing == nil {
+				_, err = bb.Create(all...)
+			}
+			bb.createMu.Unlock()
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				if err := parentChange(existing, c.bc); err != nil {
+					return err
+				}
+				if err := existing.Reconfigure(append(resetOptions(), c.opts...)...); err != nil {
+					return err
+				}
+			}
+		}
+		// Validate guarantees progress, since there are no cycles or unknown parents
+		pending = deferred
+	}
+
+	return nil
+}
+
+// parentChange reports a ConfigError if bc declares a different parent
+// than the existing breaker b has.
+func parentChange(b *Breaker, bc BreakerConfig) error {
+	var current string
+	if b.parent != nil {
+		current = b.parent.name
+	}
+	if current == bc.Parent {
+		return nil
+	}
+	return ConfigError{
+		Breaker: bc.Name,
+		Field:   "parent",
+		Reason:  fmt.Sprintf("cannot change the parent of an existing breaker from %q to %q", current, bc.Parent),
+	}
+}
+
+// validateOptions checks opts as NewBreaker would, without creating a breaker.
+func validateOptions(opts ...Option) error {
+	b := &Breaker{}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b.validate()
+}
+
+// resetOptions returns options that revert every declarable setting to its default.
+func resetOptions() []Option {
+	return []Option{
+		WithTimeout(0),
+		WithBackOff(0),
+		WithWindow(0),
+		WithThreshold(0),
+		WithLockOut(0),
+		WithEstimationFunc(nil),
+		WithOpeningResetsErrors(false),
+		WithDeterministicThrottle(false),
+		WithStrict(false),
+		WithStats(0),
+	}
+}
@@ -0,0 +1,536 @@
+package circuit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+		d := Duration(90 * time.Second)
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(data) != `"1m30s"` {
+			t.Fatalf("expected \"1m30s\", got %s", data)
+		}
+		var got Duration
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != d {
+			t.Fatalf("expected %v, got %v", d, got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		var d Duration
+		if err := json.Unmarshal([]byte(`"ten seconds"`), &d); err == nil {
+			t.Fatal("expected error for invalid duration")
+		}
+	})
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid document", func(t *testing.T) {
+		t.Parallel()
+		doc := `{
+			"breakers": [
+				{"name": "users/get", "parent": "users", "threshold": 3, "estimation": "ease-in-out"},
+				{"name": "users", "timeout": "2s", "backoff": "30s", "window": "1m", "lockout": "5s", "threshold": 10, "opening_resets_errors": true}
+			]
+		}`
+		cfg, err := ParseConfig([]byte(doc), json.Unmarshal)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.Breakers) != 2 {
+			t.Fatalf("expected 2 breakers, got %d", len(cfg.Breakers))
+		}
+		users := cfg.Breakers[1]
+		if time.Duration(users.BackOff) != 30*time.Second {
+			t.Fatalf("expected 30s backoff, got %v", time.Duration(users.BackOff))
+		}
+		if !users.OpeningResetsErrors {
+			t.Fatal("expected opening_resets_errors to be true")
+		}
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseConfig([]byte(`{"breakers": [{"name": "a", "window": "soon"}]}`), json.Unmarshal)
+		if err == nil || !strings.Contains(err.Error(), "soon") {
+			t.Fatalf("expected decode error mentioning the bad value, got %v", err)
+		}
+	})
+
+	t.Run("strict JSON", func(t *testing.T) {
+		t.Parallel()
+		doc := []byte(`{"breakers": [{"name": "a", "treshold": 3}]}`)
+		if _, err := ParseConfig(doc, json.Unmarshal); err != nil {
+			t.Fatalf("expected json.Unmarshal to ignore the unknown key, got %v", err)
+		}
+		_, err := ParseConfig(doc, UnmarshalStrictJSON)
+		if err == nil || !strings.Contains(err.Error(), "treshold") {
+			t.Fatalf("expected an error naming the unknown key, got %v", err)
+		}
+		if _, err := ParseConfig([]byte(`{"breakers": []} {}`), UnmarshalStrictJSON); err == nil {
+			t.Fatal("expected an error for trailing data")
+		}
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		t.Parallel()
+		doc := `
+breakers:
+  - name: users
+    timeout: 2s
+    backoff: 30s
+    window: 1m
+    lockout: 5s
+    threshold: 10
+    opening_resets_errors: true
+  - name: users/get
+    parent: users
+    estimation: ease-in-out
+    smooth: true
+    stats_window: 1m
+`
+		got, err := ParseConfig([]byte(doc), unmarshalYAML)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want, err := ParseConfig([]byte(`{"breakers": [
+			{"name": "users", "timeout": "2s", "backoff": "30s", "window": "1m", "lockout": "5s", "threshold": 10, "opening_resets_errors": true},
+			{"name": "users/get", "parent": "users", "estimation": "ease-in-out", "smooth": true, "stats_window": "1m"}
+		]}`), UnmarshalStrictJSON)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got.Breakers) != 2 || got.Breakers[0] != want.Breakers[0] || got.Breakers[1] != want.Breakers[1] {
+			t.Fatalf("expected the YAML document to match the JSON one, got %+v", got)
+		}
+	})
+
+	t.Run("tags agree across formats", func(t *testing.T) {
+		t.Parallel()
+		for _, typ := range []reflect.Type{reflect.TypeOf(BoxConfig{}), reflect.TypeOf(BreakerConfig{})} {
+			for i := 0; i < typ.NumField(); i++ {
+				f := typ.Field(i)
+				j := f.Tag.Get("json")
+				if y, tm := f.Tag.Get("yaml"), f.Tag.Get("toml"); y != j || tm != j {
+					t.Errorf("%s.%s: json %q, yaml %q, toml %q", typ.Name(), f.Name, j, y, tm)
+				}
+			}
+		}
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		t.Parallel()
+		doc := `{
+			"breakers": [
+				{"name": "", "threshold": 1},
+				{"name": "a", "window": "-1s", "estimation": "wobbly"},
+				{"name": "a"},
+				{"name": "b", "parent": "missing"},
+				{"name": "c", "parent": "d"},
+				{"name": "d", "parent": "c"}
+			]
+		}`
+		_, err := ParseConfig([]byte(doc), json.Unmarshal)
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+		for _, want := range []string{
+			"name: must not be empty",
+			`breaker "a": window: must not be negative`,
+			`unknown estimation function "wobbly"`,
+			"duplicate breaker name",
+			`unknown parent "missing"`,
+			`breaker "c": parent: parent chain forms a cycle`,
+			`breaker "d": parent: parent chain forms a cycle`,
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got:\n%v", want, err)
+			}
+		}
+		var cfgErr ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Fatal("expected ConfigError type")
+		}
+	})
+}
+
+func TestNewBreakerBoxFromConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("builds breakers and parents", func(t *testing.T) {
+		t.Parallel()
+		cfg := BoxConfig{Breakers: []BreakerConfig{
+			{Name: "users/get", Parent: "users", Threshold: 3, Estimation: "exponential"},
+			{Name: "users", BackOff: Duration(30 * time.Second), LockOut: Duration(5 * time.Second)},
+		}}
+		m := &mockMetrics{}
+		bb, err := NewBreakerBoxFromConfig(cfg, WithMetrics(m), WithThreshold(7))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		users := bb.Load("users")
+		get := bb.Load("users/get")
+		if users == nil || get == nil {
+			t.Fatal("expected both breakers in the box")
+		}
+		if get.Parent() != users {
+			t.Fatal("expected users/get to be a child of users")
+		}
+		if get.threshold != 3 {
+			t.Fatalf("expected declared threshold 3, got %d", get.threshold)
+		}
+		if users.threshold != 7 {
+			t.Fatalf("expected programmatic threshold 7 when undeclared, got %d", users.threshold)
+		}
+		if users.backoff != 30*time.Second || users.lockout != 5*time.Second {
+			t.Fatalf("unexpected timings: backoff=%v lockout=%v", users.backoff, users.lockout)
+		}
+		if users.timeout != DefaultTimeout {
+			t.Fatalf("expected default timeout, got %v", users.timeout)
+		}
+		if get.metrics != m {
+			t.Fatal("expected programmatic options to apply to every breaker")
+		}
+		if get.estimate(1) != Exponential(1) {
+			t.Fatal("expected exponential estimation")
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewBreakerBoxFromConfig(BoxConfig{Breakers: []BreakerConfig{{Name: "a", Parent: "a"}}})
+		if err == nil {
+			t.Fatal("expected error for self-parent")
+		}
+	})
+
+	t.Run("registered estimation func", func(t *testing.T) {
+		t.Parallel()
+		RegisterEstimationFunc("test-half-open", func(tick int) uint32 {
+			if tick <= 50 {
+				return 100
+			}
+			return 0
+		})
+		bb, err := NewBreakerBoxFromConfig(BoxConfig{Breakers: []BreakerConfig{{Name: "a", Estimation: "test-half-open"}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bb.Load("a").estimate(10) != 100 {
+			t.Fatal("expected registered estimation func to be used")
+		}
+	})
+
+	t.Run("registered curves are told apart", func(t *testing.T) {
+		t.Parallel()
+		steep, _ := Power(4)
+		shallow, _ := Power(0.25)
+		RegisterEstimationFunc("test-power-steep", steep)
+		RegisterEstimationFunc("test-power-shallow", shallow)
+		bb, err := NewBreakerBoxFromConfig(BoxConfig{Breakers: []BreakerConfig{
+			{Name: "test-power-steep", Estimation: "test-power-steep"},
+			{Name: "test-power-shallow", Estimation: "test-power-shallow"},
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, name := range []string{"test-power-steep", "test-power-shallow"} {
+			if got := bb.Load(name).Config().Estimation; got != name {
+				t.Fatalf("expected estimation %q, got %q", name, got)
+			}
+		}
+
+		// set directly, only the built-ins are known by name
+		if got := mustNewBreaker(t, WithEstimationFunc(steep)).Config().Estimation; got != CustomEstimation {
+			t.Fatalf("expected estimation %q, got %q", CustomEstimation, got)
+		}
+		if got := mustNewBreaker(t, WithEstimationFunc(Exponential)).Config().Estimation; got != "exponential" {
+			t.Fatalf("expected estimation %q, got %q", "exponential", got)
+		}
+	})
+}
+
+func TestConfigSmooth(t *testing.T) {
+	t.Parallel()
+
+	t.Run("selects the probability func", func(t *testing.T) {
+		t.Parallel()
+		RegisterProbabilityFunc("test-smooth-only", func(p float64) float64 { return 1 - p })
+		RegisterEstimationFunc("test-smooth-steps", func(tick int) uint32 { return uint32(100 - tick/10*10) })
+		bb, err := NewBreakerBoxFromConfig(BoxConfig{Breakers: []BreakerConfig{
+			{Name: "default", Smooth: true},
+			{Name: "builtin", Estimation: "ease-in-out", Smooth: true},
+			{Name: "interpolated", Estimation: "test-smooth-steps", Smooth: true},
+			{Name: "registered", Estimation: "test-smooth-only", Smooth: true},
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for name, want := range map[string]string{
+			"default":      "linear",
+			"builtin":      "ease-in-out",
+			"interpolated": "test-smooth-steps",
+			"registered":   "test-smooth-only",
+		} {
+			c := bb.Load(name).Config()
+			if !c.Smooth || c.Estimation != want {
+				t.Fatalf("%s: expected smooth %q, got %+v", name, want, c)
+			}
+		}
+	})
+
+	t.Run("unknown names", func(t *testing.T) {
+		t.Parallel()
+		RegisterProbabilityFunc("test-smooth-unknown", LinearProbability)
+		err := BreakerConfig{Name: "a", Estimation: "test-smooth-unknown"}.Validate()
+		if err == nil {
+			t.Fatal("expected a probability func name to need smooth")
+		}
+		if err := (BreakerConfig{Name: "a", Estimation: "nope", Smooth: true}).Validate(); err == nil {
+			t.Fatal("expected an unknown name to be rejected")
+		}
+	})
+}
+
+func TestBreakerBox_ApplyConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reconfigures existing and creates new", func(t *testing.T) {
+		t.Parallel()
+		var reconfigured []string
+		bb, err := NewBreakerBoxFromConfig(BoxConfig{Breakers: []BreakerConfig{
+			{Name: "users", Threshold: 5, BackOff: Duration(30 * time.Second)},
+		}}, WithOnConfigChange(func(name string) {
+			reconfigured = append(reconfigured, name)
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		users := bb.Load("users")
+		users.tracker.incr()
+
+		err = bb.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{
+			{Name: "users", Threshold: 2},
+			{Name: "users/get", Parent: "users", Threshold: 1},
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if bb.Load("users") != users {
+			t.Fatal("expected existing breaker to be reconfigured in place")
+		}
+		if users.threshold != 2 {
+			t.Fatalf("expected threshold 2, got %d", users.threshold)
+		}
+		if users.backoff != DefaultBackOff {
+			t.Fatalf("expected undeclared backoff to revert to default, got %v", users.backoff)
+		}
+		if users.Size() != 1 {
+			t.Fatalf("expected recorded errors to survive, got %d", users.Size())
+		}
+		if len(reconfigured) != 1 || reconfigured[0] != "users" {
+			t.Fatalf("expected config change event for users, got %v", reconfigured)
+		}
+		get := bb.Load("users/get")
+		if get == nil || get.Parent() != users {
+			t.Fatal("expected users/get to be created as a child of users")
+		}
+	})
+
+	t.Run("undeclared switches revert", func(t *testing.T) {
+		t.Parallel()
+		bb, err := NewBreakerBoxFromConfig(BoxConfig{Breakers: []BreakerConfig{
+			{Name: "a", DeterministicThrottle: true, Strict: true, OpeningResetsErrors: true},
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := bb.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{{Name: "a"}}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c := bb.Load("a").Config(); c.DeterministicThrottle || c.Strict || c.OpeningResetsErrors {
+			t.Fatalf("expected every switch to revert, got %+v", c)
+		}
+	})
+
+	t.Run("invalid document changes nothing", func(t *testing.T) {
+		t.Parallel()
+		bb := NewBreakerBox()
+		b, _ := bb.Create(WithName("a"), WithThreshold(4))
+		err := bb.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{
+			{Name: "a", Threshold: 1},
+			{Name: "b", Estimation: "nope"},
+		}})
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+		if b.threshold != 4 {
+			t.Fatalf("expected threshold to be unchanged, got %d", b.threshold)
+		}
+	})
+
+	t.Run("concurrent LoadOrCreate keeps its breaker", func(t *testing.T) {
+		t.Parallel()
+		for range 100 {
+			bb := NewBreakerBox()
+			var (
+				wg  sync.WaitGroup
+				got *Breaker
+			)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, _ = bb.LoadOrCreate("a")
+			}()
+			if err := bb.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{{Name: "a", Threshold: 3}}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			wg.Wait()
+			if bb.Load("a") != got {
+				t.Fatal("expected the breaker returned by LoadOrCreate to stay in the box")
+			}
+			if got.threshold != 3 {
+				t.Fatalf("expected the document to apply, got threshold %d", got.threshold)
+			}
+		}
+	})
+
+	t.Run("parent change is rejected", func(t *testing.T) {
+		t.Parallel()
+		bb, err := NewBreakerBoxFromConfig(BoxConfig{Breakers: []BreakerConfig{
+			{Name: "users"},
+			{Name: "orders"},
+			{Name: "users/get", Parent: "users", Threshold: 4},
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		get := bb.Load("users/get")
+		for _, parent := range []string{"orders", ""} {
+			err = bb.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{
+				{Name: "users"},
+				{Name: "orders"},
+				{Name: "users/get", Parent: parent, Threshold: 1},
+			}})
+			var ce ConfigError
+			if !errors.As(err, &ce) || ce.Field != "parent" {
+				t.Fatalf("expected a parent ConfigError, got %v", err)
+			}
+		}
+		if get.Parent() != bb.Load("users") || get.threshold != 4 {
+			t.Fatal("expected users/get to be unchanged")
+		}
+	})
+
+	t.Run("invalid options change nothing", func(t *testing.T) {
+		t.Parallel()
+		bb := NewBreakerBox()
+		a, _ := bb.Create(WithName("a"), WithThreshold(4))
+		strict, _ := bb.Create(WithName("strict"), WithStrict(true))
+		for _, bc := range []BreakerConfig{
+			// a valid document whose options fail strict validation
+			{Name: "b", Strict: true, BackOff: Duration(time.Millisecond)},
+			{Name: "strict", Strict: true, Window: Duration(time.Minute), LockOut: Duration(time.Hour)},
+		} {
+			err := bb.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{{Name: "a", Threshold: 7}, bc}})
+			if !errors.Is(err, ErrBelowMinimum) && !errors.Is(err, ErrLockoutExceedsWindow) {
+				t.Fatalf("%s: expected an option error, got %v", bc.Name, err)
+			}
+			if !strings.Contains(err.Error(), bc.Name) {
+				t.Fatalf("expected the error to name %q, got %v", bc.Name, err)
+			}
+		}
+		if a.threshold != 4 || strict.lockout != 0 || bb.Load("b") != nil {
+			t.Fatal("expected no breaker to change")
+		}
+	})
+}
+
+// unmarshalYAML decodes the subset of YAML used by configuration
+// documents: a "breakers" list of flat mappings. Like YAML libraries,
+// it matches keys by yaml tag and decodes durations as text, so it
+// stands in for one without adding a dependency.
+func unmarshalYAML(data []byte, v any) error {
+	cfg, ok := v.(*BoxConfig)
+	if !ok {
+		return fmt.Errorf("yaml: cannot decode into %T", v)
+	}
+
+	fields := map[string]int{}
+	typ := reflect.TypeOf(BreakerConfig{})
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		fields[name] = i
+	}
+
+	for n, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case trimmed == "breakers:":
+			continue
+		case strings.HasPrefix(trimmed, "- "):
+			cfg.Breakers = append(cfg.Breakers, BreakerConfig{})
+			trimmed = strings.TrimPrefix(trimmed, "- ")
+		}
+		if len(cfg.Breakers) == 0 {
+			return fmt.Errorf("yaml: line %d: expected a breakers list", n+1)
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return fmt.Errorf("yaml: line %d: expected key: value", n+1)
+		}
+		i, known := fields[key]
+		if !known {
+			continue // like YAML libraries, ignore unknown keys by default
+		}
+		field := reflect.ValueOf(&cfg.Breakers[len(cfg.Breakers)-1]).Elem().Field(i)
+		value = strings.TrimSpace(value)
+		switch field.Addr().Interface().(type) {
+		case *Duration:
+			if err := field.Addr().Interface().(*Duration).UnmarshalText([]byte(value)); err != nil {
+				return err
+			}
+		case *string:
+			field.SetString(value)
+		case *bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("yaml: line %d: %w", n+1, err)
+			}
+			field.SetBool(b)
+		case *uint32:
+			u, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return fmt.Errorf("yaml: line %d: %w", n+1, err)
+			}
+			field.SetUint(u)
+		}
+	}
+	return nil
+}
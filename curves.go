@@ -0,0 +1,233 @@
+package circuit
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// Point is a point on an estimation curve. X is the tick, in [0, 100],
+// and Y is the probability, in percent, that a request is blocked.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// CubicBezier builds an EstimationFunc from a cubic Bézier curve that
+// starts at (0, 100), ends at (100, 0) and is shaped by the control
+// points p1 and p2. Both control points must have X in [0, 100]; their
+// Y values may lie outside [0, 100] as long as the curve itself does not.
+func CubicBezier(p1, p2 Point) (EstimationFunc, error) {
+	for _, p := range []Point{p1, p2} {
+		if !inRange(p.X) {
+			return nil, curveError("CubicBezier", fmt.Sprintf("control point x %g not in [0, 100]", p.X), ErrOutOfRange)
+		}
+	}
+
+	bezier := func(a, b, t float64) float64 {
+		u := 1 - t
+		return 3*u*u*t*a + 3*u*t*t*b
+	}
+	return tabulate("CubicBezier", func(x float64) float64 {
+		// x(t) is non-decreasing, so find t by bisection
+		lo, hi := 0.0, 1.0
+		for i := 0; i < 50; i++ {
+			t := (lo + hi) / 2
+			if bezier(p1.X, p2.X, t)+t*t*t*100 < x {
+				lo = t
+			} else {
+				hi = t
+			}
+		}
+		t := (lo + hi) / 2
+		u := 1 - t
+		return u*u*u*100 + bezier(p1.Y, p2.Y, t)
+	})
+}
+
+// PiecewiseLinear builds an EstimationFunc that interpolates linearly
+// between keyframes. The keyframes must be ordered by strictly
+// increasing X, within [0, 100]. Before the first keyframe and after the
+// last, the curve holds their values.
+func PiecewiseLinear(keyframes ...Point) (EstimationFunc, error) {
+	if err := checkKeyframes("PiecewiseLinear", keyframes); err != nil {
+		return nil, err
+	}
+
+	return tabulate("PiecewiseLinear", func(x float64) float64 {
+		if x <= keyframes[0].X {
+			return keyframes[0].Y
+		}
+		for i := 1; i < len(keyframes); i++ {
+			a, b := keyframes[i-1], keyframes[i]
+			if x <= b.X {
+				return a.Y + (b.Y-a.Y)*(x-a.X)/(b.X-a.X)
+			}
+		}
+		return keyframes[len(keyframes)-1].Y
+	})
+}
+
+// Step builds an EstimationFunc that holds the value of each keyframe
+// until the next one. The keyframes must be ordered by strictly
+// increasing X, within [0, 100]. Before the first keyframe, the curve
+// holds its value. For example, a traditional half-open breaker that
+// blocks everything for the first half of the backoff is
+//
+//	Step(Point{X: 0, Y: 100}, Point{X: 51, Y: 0})
+func Step(keyframes ...Point) (EstimationFunc, error) {
+	if err := checkKeyframes("Step", keyframes); err != nil {
+		return nil, err
+	}
+
+	return tabulate("Step", func(x float64) float64 {
+		y := keyframes[0].Y
+		for _, k := range keyframes[1:] {
+			if x < k.X {
+				break
+			}
+			y = k.Y
+		}
+		return y
+	})
+}
+
+// Power builds an EstimationFunc that blocks 100 * (1 - (tick/100)^exponent)
+// percent of requests. An exponent of 1 is Linear; larger exponents keep
+// blocking for longer, and smaller ones recover sooner. The exponent must
+// be positive.
+func Power(exponent float64) (EstimationFunc, error) {
+	if !(exponent > 0) || math.IsInf(exponent, 1) {
+		return nil, curveError("Power", fmt.Sprintf("exponent %g is not positive and finite", exponent), ErrOutOfRange)
+	}
+
+	return tabulate("Power", func(x float64) float64 {
+		return 100 * (1 - math.Pow(x/100, exponent))
+	})
+}
+
+// Jitter returns f with up to ±amount added to each result at random,
+// clamped to [0, 100]. JitteredLinear is Jitter(Linear, 5, nil).
+// The jitter is drawn from src, or from the global source in math/rand/v2
+// if src is nil; a breaker's WithRand source is never used. To make a
+// breaker's throttling reproducible, pass a seeded source here as well
+// as to WithRand; the two must be separate.
+// The curve serialises its use of src, so it is safe for concurrent use.
+func Jitter(f EstimationFunc, amount uint32, src rand.Source) (EstimationFunc, error) {
+	if err := checkCurve("Jitter", f); err != nil {
+		return nil, err
+	}
+	if amount > 100 {
+		return nil, curveError("Jitter", fmt.Sprintf("amount %d > 100", amount), ErrOutOfRange)
+	}
+
+	n := int(amount)
+	r := newCurveRand(src)
+	return func(tick int) uint32 {
+		v := int(f(clampTick(tick))) + r.IntN(2*n+1) - n
+		return uint32(min(max(v, 0), 100))
+	}, nil
+}
+
+// Clamp returns f with each result limited to [lo, hi], e.g. to always
+// let a few requests through, or to never fully recover before the
+// backoff ends. lo must not exceed hi, and hi must not exceed 100.
+func Clamp(f EstimationFunc, lo, hi uint32) (EstimationFunc, error) {
+	if err := checkCurve("Clamp", f); err != nil {
+		return nil, err
+	}
+	if lo > hi || hi > 100 {
+		return nil, curveError("Clamp", fmt.Sprintf("[%d, %d] not within [0, 100]", lo, hi), ErrOutOfRange)
+	}
+
+	return func(tick int) uint32 {
+		return min(max(f(clampTick(tick)), lo), hi)
+	}, nil
+}
+
+// Invert returns f with each result subtracted from 100, so that
+// a curve that blocks is turned into one that admits.
+func Invert(f EstimationFunc) (EstimationFunc, error) {
+	if err := checkCurve("Invert", f); err != nil {
+		return nil, err
+	}
+
+	return func(tick int) uint32 {
+		return 100 - f(clampTick(tick))
+	}, nil
+}
+
+// Blend returns a weighted average of a and b. A weight of 0 is a,
+// a weight of 1 is b, and the weight must be within [0, 1].
+func Blend(a, b EstimationFunc, weight float64) (EstimationFunc, error) {
+	for _, f := range []EstimationFunc{a, b} {
+		if err := checkCurve("Blend", f); err != nil {
+			return nil, err
+		}
+	}
+	if !(weight >= 0 && weight <= 1) {
+		return nil, curveError("Blend", fmt.Sprintf("weight %g not in [0, 1]", weight), ErrOutOfRange)
+	}
+
+	return func(tick int) uint32 {
+		tick = clampTick(tick)
+		return uint32(math.Round((1-weight)*float64(a(tick)) + weight*float64(b(tick))))
+	}, nil
+}
+
+// tabulate evaluates y at every tick and returns an EstimationFunc that
+// looks the results up, like the built-in curves. Results are rounded,
+// and an error is returned if any is outside [0, 100].
+func tabulate(name string, y func(x float64) float64) (EstimationFunc, error) {
+	var table [100]uint32
+	for tick := 1; tick <= 100; tick++ {
+		v := math.Round(y(float64(tick)))
+		if !inRange(v) {
+			return nil, curveError(name, fmt.Sprintf("tick %d evaluates to %g", tick, v), ErrEstimationRange)
+		}
+		table[tick-1] = uint32(v)
+	}
+
+	return func(tick int) uint32 {
+		return table[clampTick(tick)-1]
+	}, nil
+}
+
+// checkKeyframes verifies that keyframes is not empty and is ordered
+// by strictly increasing X, within [0, 100].
+func checkKeyframes(name string, keyframes []Point) error {
+	if len(keyframes) == 0 {
+		return curveError(name, "no keyframes", ErrOutOfRange)
+	}
+	for i, k := range keyframes {
+		if !inRange(k.X) {
+			return curveError(name, fmt.Sprintf("keyframe x %g not in [0, 100]", k.X), ErrOutOfRange)
+		}
+		if i > 0 && k.X <= keyframes[i-1].X {
+			return curveError(name, fmt.Sprintf("keyframe x %g does not follow %g", k.X, keyframes[i-1].X), ErrOutOfRange)
+		}
+	}
+	return nil
+}
+
+// checkCurve verifies that f is set and stays within [0, 100] at every tick.
+func checkCurve(name string, f EstimationFunc) error {
+	if f == nil {
+		return curveError(name, "nil estimation function", ErrOutOfRange)
+	}
+	return checkTicks(name, f, allTicks)
+}
+
+// clampTick limits tick to [1, 100], so that built curves, like
+// the throttle itself, treat ticks outside the backoff as its ends.
+func clampTick(tick int) int {
+	return min(max(tick, 1), 100)
+}
+
+func inRange(v float64) bool {
+	return v >= 0 && v <= 100
+}
+
+func curveError(name, detail string, err error) error {
+	return OptionError{Option: name, Detail: detail, Err: err}
+}
@@ -0,0 +1,222 @@
+package circuit
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestCurveBuilders(t *testing.T) {
+	t.Parallel()
+
+	// within checks that f stays within [0, 100] at every tick,
+	// including ticks outside the backoff.
+	within := func(t *testing.T, f EstimationFunc) {
+		t.Helper()
+		for tick := -1; tick <= 101; tick++ {
+			if v := f(tick); v > 100 {
+				t.Fatalf("tick %d returned %d", tick, v)
+			}
+		}
+	}
+
+	// must returns a function that accepts a builder's results,
+	// so that must(t)(Power(2)) fails t if the builder does.
+	must := func(t *testing.T) func(EstimationFunc, error) EstimationFunc {
+		return func(f EstimationFunc, err error) EstimationFunc {
+			t.Helper()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			within(t, f)
+			return f
+		}
+	}
+
+	t.Run("CubicBezier", func(t *testing.T) {
+		t.Parallel()
+		// control points on the diagonal make a straight line
+		f := must(t)(CubicBezier(Point{X: 100.0 / 3, Y: 200.0 / 3}, Point{X: 200.0 / 3, Y: 100.0 / 3}))
+		for tick := 1; tick <= 100; tick++ {
+			if v, want := f(tick), Linear(tick); v != want {
+				t.Fatalf("tick %d: expected %d, got %d", tick, want, v)
+			}
+		}
+
+		// an ease-in-out curve is non-increasing and steepest in the middle
+		f = must(t)(CubicBezier(Point{X: 50, Y: 100}, Point{X: 50, Y: 0}))
+		for tick := 2; tick <= 100; tick++ {
+			if f(tick) > f(tick-1) {
+				t.Fatalf("expected a non-increasing curve at tick %d", tick)
+			}
+		}
+		if f(10) < 95 || f(90) > 5 {
+			t.Fatalf("expected flat ends, got %d and %d", f(10), f(90))
+		}
+	})
+
+	t.Run("PiecewiseLinear", func(t *testing.T) {
+		t.Parallel()
+		f := must(t)(PiecewiseLinear(Point{X: 10, Y: 100}, Point{X: 20, Y: 50}, Point{X: 80, Y: 20}))
+		for tick, want := range map[int]uint32{1: 100, 10: 100, 15: 75, 20: 50, 50: 35, 80: 20, 100: 20} {
+			if v := f(tick); v != want {
+				t.Fatalf("tick %d: expected %d, got %d", tick, want, v)
+			}
+		}
+	})
+
+	t.Run("Step", func(t *testing.T) {
+		t.Parallel()
+		f := must(t)(Step(Point{X: 0, Y: 100}, Point{X: 51, Y: 0}))
+		for tick := 1; tick <= 100; tick++ {
+			want := uint32(100)
+			if tick > 50 {
+				want = 0
+			}
+			if v := f(tick); v != want {
+				t.Fatalf("tick %d: expected %d, got %d", tick, want, v)
+			}
+		}
+
+		f = must(t)(Step(Point{X: 30, Y: 80}, Point{X: 60, Y: 40}))
+		for tick, want := range map[int]uint32{1: 80, 30: 80, 59: 80, 60: 40, 100: 40} {
+			if v := f(tick); v != want {
+				t.Fatalf("tick %d: expected %d, got %d", tick, want, v)
+			}
+		}
+	})
+
+	t.Run("Power", func(t *testing.T) {
+		t.Parallel()
+		f := must(t)(Power(1))
+		for tick := 1; tick <= 100; tick++ {
+			if v, want := f(tick), Linear(tick); v != want {
+				t.Fatalf("tick %d: expected %d, got %d", tick, want, v)
+			}
+		}
+
+		slow := must(t)(Power(3))
+		fast := must(t)(Power(0.5))
+		if slow(50) <= Linear(50) || fast(50) >= Linear(50) {
+			t.Fatalf("expected exponents to bend the curve, got %d and %d", slow(50), fast(50))
+		}
+	})
+
+	t.Run("Jitter", func(t *testing.T) {
+		t.Parallel()
+		f := must(t)(Jitter(Linear, 5, nil))
+		seen := map[uint32]bool{}
+		for i := 0; i < 200; i++ {
+			v := f(50)
+			if v < 45 || v > 55 {
+				t.Fatalf("expected 50±5, got %d", v)
+			}
+			seen[v] = true
+		}
+		if len(seen) < 2 {
+			t.Fatal("expected jitter to vary the result")
+		}
+
+		f = must(t)(Jitter(Linear, 100, nil))
+		for i := 0; i < 200; i++ {
+			within(t, f)
+		}
+
+		// a seeded curve replays, even when wrapped
+		seeded := func(seed uint64) EstimationFunc {
+			return must(t)(Clamp(must(t)(Jitter(Linear, 20, rand.NewPCG(seed, seed))), 10, 90))
+		}
+		a, b, c := seeded(1), seeded(1), seeded(2)
+		differ := false
+		for tick := 1; tick <= 100; tick++ {
+			va, vb, vc := a(tick), b(tick), c(tick)
+			if va != vb {
+				t.Fatalf("tick %d: expected the same seed to replay, got %d and %d", tick, va, vb)
+			}
+			differ = differ || va != vc
+		}
+		if !differ {
+			t.Fatal("expected a different seed to vary the curve")
+		}
+	})
+
+	t.Run("Clamp", func(t *testing.T) {
+		t.Parallel()
+		f := must(t)(Clamp(Linear, 10, 90))
+		for tick, want := range map[int]uint32{1: 90, 50: 50, 100: 10} {
+			if v := f(tick); v != want {
+				t.Fatalf("tick %d: expected %d, got %d", tick, want, v)
+			}
+		}
+	})
+
+	t.Run("Invert", func(t *testing.T) {
+		t.Parallel()
+		f := must(t)(Invert(Linear))
+		for tick := 1; tick <= 100; tick++ {
+			if v := f(tick); v != uint32(tick) {
+				t.Fatalf("tick %d: expected %d, got %d", tick, tick, v)
+			}
+		}
+	})
+
+	t.Run("Blend", func(t *testing.T) {
+		t.Parallel()
+		zero := func(int) uint32 { return 0 }
+		f := must(t)(Blend(Linear, zero, 0.25))
+		if v := f(20); v != 60 {
+			t.Fatalf("expected 60, got %d", v)
+		}
+		if f := must(t)(Blend(Linear, zero, 0)); f(20) != 80 {
+			t.Fatalf("expected a weight of 0 to be the first curve, got %d", f(20))
+		}
+	})
+
+	t.Run("composes", func(t *testing.T) {
+		t.Parallel()
+		base := must(t)(Power(2))
+		clamped := must(t)(Clamp(base, 5, 100))
+		f := must(t)(Jitter(clamped, 3, nil))
+		if _, err := NewBreaker(WithEstimationFunc(f)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		tooHigh := func(int) uint32 { return 101 }
+		panics := func(int) uint32 { panic("boom") }
+
+		for _, tc := range []struct {
+			name    string
+			build   func() (EstimationFunc, error)
+			builder string
+			wantErr error
+		}{
+			{"bezier control x", func() (EstimationFunc, error) { return CubicBezier(Point{X: -1}, Point{X: 50}) }, "CubicBezier", ErrOutOfRange},
+			{"bezier overshoot", func() (EstimationFunc, error) { return CubicBezier(Point{X: 0, Y: 300}, Point{X: 100, Y: 0}) }, "CubicBezier", ErrEstimationRange},
+			{"no keyframes", func() (EstimationFunc, error) { return PiecewiseLinear() }, "PiecewiseLinear", ErrOutOfRange},
+			{"unordered keyframes", func() (EstimationFunc, error) { return PiecewiseLinear(Point{X: 50}, Point{X: 50}) }, "PiecewiseLinear", ErrOutOfRange},
+			{"keyframe value", func() (EstimationFunc, error) { return Step(Point{X: 0, Y: -10}) }, "Step", ErrEstimationRange},
+			{"keyframe x", func() (EstimationFunc, error) { return Step(Point{X: 101}) }, "Step", ErrOutOfRange},
+			{"power exponent", func() (EstimationFunc, error) { return Power(0) }, "Power", ErrOutOfRange},
+			{"jitter amount", func() (EstimationFunc, error) { return Jitter(Linear, 101, nil) }, "Jitter", ErrOutOfRange},
+			{"clamp bounds", func() (EstimationFunc, error) { return Clamp(Linear, 60, 40) }, "Clamp", ErrOutOfRange},
+			{"invert out of range", func() (EstimationFunc, error) { return Invert(tooHigh) }, "Invert", ErrEstimationRange},
+			{"invert nil", func() (EstimationFunc, error) { return Invert(nil) }, "Invert", ErrOutOfRange},
+			{"blend panics", func() (EstimationFunc, error) { return Blend(Linear, panics, 0.5) }, "Blend", ErrEstimationPanic},
+			{"blend weight", func() (EstimationFunc, error) { return Blend(Linear, Linear, 2) }, "Blend", ErrOutOfRange},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				f, err := tc.build()
+				if f != nil || !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				var optErr OptionError
+				if !errors.As(err, &optErr) || optErr.Option != tc.builder {
+					t.Fatalf("expected an OptionError for %s, got %v", tc.builder, err)
+				}
+			})
+		}
+	})
+}
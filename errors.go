@@ -38,4 +38,35 @@ var (
 	ErrStateOpen      = Error{msg: "circuit: the circuit breaker is open"}
 	ErrStateThrottled = Error{msg: "circuit: breaker is throttled"}
 	ErrUnnamedBreaker = Error{msg: "circuit: breakers used in a breaker box must have a name"}
+	ErrParentNotFound = Error{msg: "circuit: parent breaker not found in breaker box"}
+	ErrStateMismatch  = Error{msg: "circuit: exported state cannot be restored"}
+
+	ErrNegativeDuration     = Error{msg: "circuit: duration must not be negative"}
+	ErrBelowMinimum         = Error{msg: "circuit: duration is below the minimum"}
+	ErrThresholdOverflow    = Error{msg: "circuit: threshold can never be exceeded"}
+	ErrLockoutExceedsWindow = Error{msg: "circuit: lockout is longer than the error window"}
+	ErrEstimationRange      = Error{msg: "circuit: estimation function returned a value outside [0, 100]"}
+	ErrEstimationPanic      = Error{msg: "circuit: estimation function panicked"}
+	ErrProbabilityRange     = Error{msg: "circuit: probability function returned a value outside [0, 1]"}
+	ErrNegativeSize         = Error{msg: "circuit: size must not be negative"}
+	ErrOutOfRange           = Error{msg: "circuit: value is out of range"}
 )
+
+// OptionError reports an invalid option passed to NewBreaker or Reconfigure,
+// or an invalid argument to an EstimationFunc builder such as CubicBezier.
+// It wraps one of the option sentinel errors, so it can be matched with
+// errors.Is (e.g. errors.Is(err, ErrNegativeDuration)).
+type OptionError struct {
+	Option string // Name of the offending option or builder, e.g. "WithBackOff"
+	Detail string // The offending value or other context
+	Err    error  // The underlying sentinel error
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("%s (%s: %s)", e.Err, e.Option, e.Detail)
+}
+
+// Unwrap returns the underlying sentinel error.
+func (e OptionError) Unwrap() error {
+	return e.Err
+}
@@ -79,6 +79,16 @@ func TestError(t *testing.T) {
 			ErrStateOpen,
 			ErrStateThrottled,
 			ErrUnnamedBreaker,
+			ErrParentNotFound,
+			ErrStateMismatch,
+			ErrNegativeDuration,
+			ErrBelowMinimum,
+			ErrThresholdOverflow,
+			ErrLockoutExceedsWindow,
+			ErrEstimationRange,
+			ErrEstimationPanic,
+			ErrNegativeSize,
+			ErrOutOfRange,
 		}
 		for _, s := range sentinels {
 			if s.Error() == "" {
@@ -86,4 +96,16 @@ func TestError(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("OptionError", func(t *testing.T) {
+		t.Parallel()
+		e := OptionError{Option: "WithBackOff", Detail: "-1s", Err: ErrNegativeDuration}
+		want := "circuit: duration must not be negative (WithBackOff: -1s)"
+		if got := e.Error(); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+		if !errors.Is(e, ErrNegativeDuration) {
+			t.Fatal("expected Is to match the wrapped sentinel")
+		}
+	})
 }
@@ -0,0 +1,111 @@
+package circuit
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// defaultDampeningBase is the lockout that flap dampening doubles
+// when the breaker has no lockout of its own.
+const defaultDampeningBase = time.Second
+
+// FlapRecorder is an optional extension of MetricsCollector. If the
+// collector passed to WithMetrics also implements FlapRecorder, it is
+// notified each time the breaker starts flapping.
+type FlapRecorder interface {
+	// RecordFlap is called when a breaker makes more transitions within
+	// the flap detection period than allowed. transitions is the number
+	// of transitions within the period, including the latest one.
+	RecordFlap(breakerName string, transitions int)
+}
+
+// flapState tracks recent transitions for flap detection.
+// It is protected by the breaker's stateMX.
+type flapState struct {
+	times    []int64 // Unix nano timestamps of transitions within the period
+	flapping bool
+	reopens  uint   // transitions to Open while flapping
+	notify   uint32 // 1 if a flap has been detected but not passed to onFlap
+	count    int    // transitions within the period when the flap was detected
+}
+
+// detectFlap records an automatic transition to the given state and
+// marks the breaker as flapping once the period holds too many.
+// Must be called with stateMX held, before the transition is made.
+func (b *Breaker) detectFlap(to uint32) {
+	if b.flapTransitions == 0 {
+		return
+	}
+	f := &b.flap
+	now := b.now().UnixNano()
+	cutoff := now - int64(b.flapPeriod)
+	kept := f.times[:0]
+	for _, ts := range f.times {
+		if ts > cutoff {
+			kept = append(kept, ts)
+		}
+	}
+	f.times = append(kept, now)
+
+	if f.flapping {
+		if to == internalOpen {
+			f.reopens++
+		}
+		return
+	}
+	if len(f.times) <= b.flapTransitions {
+		return
+	}
+
+	f.flapping = true
+	f.count = len(f.times)
+	atomic.StoreUint32(&f.notify, 1)
+	if fr, ok := b.metrics.(FlapRecorder); ok {
+		fr.RecordFlap(b.name, f.count)
+	}
+}
+
+// settleFlap ends flapping once the breaker has stayed closed for the
+// stable period, which also resets any dampened lockout.
+// Must be called with stateMX held.
+func (b *Breaker) settleFlap() {
+	f := &b.flap
+	if !f.flapping || atomic.LoadUint32(&b.state) != internalClosed {
+		return
+	}
+	since, ok := b.closedStatus()
+	if !ok || b.since(since) < b.flapStable {
+		return
+	}
+	f.flapping = false
+	f.reopens = 0
+	f.times = f.times[:0]
+}
+
+// dampen lengthens a lockout of d while the breaker is flapping with
+// dampening enabled: it doubles with each reopen, starting from d (or
+// one second if d is zero), up to the dampening maximum.
+// Must be called with stateMX held.
+func (b *Breaker) dampen(d time.Duration) time.Duration {
+	if b.dampenMax == 0 || b.flap.reopens == 0 {
+		return d
+	}
+	if d == 0 {
+		d = defaultDampeningBase
+	}
+	for i := uint(0); i < b.flap.reopens && d < b.dampenMax; i++ {
+		d *= 2
+	}
+	return min(d, b.dampenMax)
+}
+
+// notifyFlap invokes the onFlap hook if a flap was detected since the
+// last call. Must be called without stateMX held.
+func (b *Breaker) notifyFlap() {
+	if atomic.CompareAndSwapUint32(&b.flap.notify, 1, 0) && b.onFlap != nil {
+		b.stateMX.Lock()
+		count := b.flap.count
+		b.stateMX.Unlock()
+		b.onFlap(b.name, count)
+	}
+}
@@ -0,0 +1,230 @@
+package circuit
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type flapMetrics struct {
+	mockMetrics
+	flaps []int
+}
+
+func (m *flapMetrics) RecordFlap(_ string, transitions int) {
+	m.mu.Lock()
+	m.flaps = append(m.flaps, transitions)
+	m.mu.Unlock()
+}
+
+// cycle drives a breaker through automatic transitions without waiting:
+// an error opens it, and expiring the lockout and clearing the errors
+// throttles it.
+func cycle(b *Breaker, to State) {
+	switch to {
+	case Open:
+		b.tracker.incr()
+	case Throttled:
+		b.stateMX.Lock()
+		if atomic.LoadInt64(&b.lockedSince) != 0 {
+			atomic.StoreInt64(&b.lockedSince, time.Now().Add(-time.Hour).UnixNano())
+		}
+		b.stateMX.Unlock()
+		b.tracker.reset(true)
+	}
+	b.State()
+}
+
+func lockoutOf(t *testing.T, b *Breaker) time.Duration {
+	t.Helper()
+	snap := b.Snapshot()
+	if snap.State != Open || snap.Opened == nil || snap.LockoutEnds == nil {
+		t.Fatalf("expected an open, locked breaker, got %+v", snap)
+	}
+	return snap.LockoutEnds.Sub(*snap.Opened)
+}
+
+func TestFlapDetection(t *testing.T) {
+	t.Parallel()
+
+	t.Run("detects too many transitions", func(t *testing.T) {
+		t.Parallel()
+		var flapped []int
+		metrics := &flapMetrics{}
+		b := mustNewBreaker(t,
+			WithName("flappy"),
+			WithFlapDetection(2, time.Minute),
+			WithMetrics(metrics),
+			WithOnFlap(func(name string, transitions int) {
+				if name != "flappy" {
+					t.Errorf("unexpected breaker name %q", name)
+				}
+				flapped = append(flapped, transitions)
+			}),
+		)
+
+		cycle(b, Open)
+		cycle(b, Throttled)
+		if b.Snapshot().Flapping || len(flapped) != 0 {
+			t.Fatal("expected no flap within the limit")
+		}
+
+		cycle(b, Open)
+		if !b.Snapshot().Flapping {
+			t.Fatal("expected breaker to be flapping")
+		}
+		if len(flapped) != 1 || flapped[0] != 3 {
+			t.Fatalf("expected one flap callback with 3 transitions, got %v", flapped)
+		}
+		if len(metrics.flaps) != 1 || metrics.flaps[0] != 3 {
+			t.Fatalf("expected one flap metric with 3 transitions, got %v", metrics.flaps)
+		}
+
+		// further transitions while flapping are not new flaps
+		cycle(b, Throttled)
+		cycle(b, Open)
+		if len(flapped) != 1 || len(metrics.flaps) != 1 {
+			t.Fatalf("expected a single flap event, got %v and %v", flapped, metrics.flaps)
+		}
+	})
+
+	t.Run("state changes report flapping", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithFlapDetection(1, time.Minute))
+		<-b.StateChange() // initial state
+
+		cycle(b, Open)
+		cycle(b, Throttled)
+		if (<-b.StateChange()).Flapping {
+			t.Fatal("expected first transition not to be flapping")
+		}
+		if !(<-b.StateChange()).Flapping {
+			t.Fatal("expected second transition to be flapping")
+		}
+	})
+
+	t.Run("old transitions leave the period", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithFlapDetection(2, 20*time.Millisecond))
+		cycle(b, Open)
+		cycle(b, Throttled)
+		time.Sleep(30 * time.Millisecond)
+		cycle(b, Open)
+		if b.Snapshot().Flapping {
+			t.Fatal("expected transitions outside the period to be ignored")
+		}
+	})
+
+	t.Run("forced transitions are not counted", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithFlapDetection(1, time.Minute))
+		b.ForceOpen()
+		b.ForceClose()
+		b.Reset()
+		b.ForceOpen()
+		if b.Snapshot().Flapping {
+			t.Fatal("expected forced transitions not to count")
+		}
+	})
+
+	t.Run("settles after a stable period", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t,
+			WithFlapDetection(1, time.Minute),
+			WithFlapDampening(time.Minute, 20*time.Millisecond),
+			WithBackOff(10*time.Millisecond),
+		)
+		cycle(b, Open)
+		cycle(b, Throttled)
+		time.Sleep(15 * time.Millisecond)
+		b.State() // throttled -> closed, after the backoff
+		if s := b.Snapshot(); s.State != Closed || !s.Flapping {
+			t.Fatalf("expected a flapping, closed breaker, got %+v", s)
+		}
+
+		time.Sleep(25 * time.Millisecond)
+		if b.Snapshot().Flapping {
+			t.Fatal("expected flapping to end after the stable period")
+		}
+	})
+}
+
+func TestFlapDampening(t *testing.T) {
+	t.Parallel()
+
+	t.Run("doubles the lockout up to the maximum", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t,
+			WithLockOut(time.Second),
+			WithFlapDetection(1, time.Minute),
+			WithFlapDampening(5*time.Second, 0),
+		)
+
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != time.Second {
+			t.Fatalf("expected the configured lockout before flapping, got %s", got)
+		}
+		for _, want := range []time.Duration{2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+			cycle(b, Throttled)
+			cycle(b, Open)
+			if got := lockoutOf(t, b); got != want {
+				t.Fatalf("expected a dampened lockout of %s, got %s", want, got)
+			}
+		}
+	})
+
+	t.Run("applies a lockout when none is configured", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t,
+			WithFlapDetection(1, time.Minute),
+			WithFlapDampening(time.Minute, 0),
+		)
+		cycle(b, Open)
+		if b.Snapshot().LockoutEnds != nil {
+			t.Fatal("expected no lockout before flapping")
+		}
+		cycle(b, Throttled)
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != 2*defaultDampeningBase {
+			t.Fatalf("expected a lockout of %s, got %s", 2*defaultDampeningBase, got)
+		}
+	})
+
+	t.Run("lockout returns to normal once stable", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t,
+			WithLockOut(time.Second),
+			WithFlapDetection(1, time.Minute),
+			WithFlapDampening(time.Minute, 20*time.Millisecond),
+			WithBackOff(10*time.Millisecond),
+		)
+		cycle(b, Open)
+		cycle(b, Throttled)
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != 2*time.Second {
+			t.Fatalf("expected a dampened lockout, got %s", got)
+		}
+
+		cycle(b, Throttled)
+		time.Sleep(15 * time.Millisecond)
+		b.State() // throttled -> closed
+		time.Sleep(25 * time.Millisecond)
+
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != time.Second {
+			t.Fatalf("expected the configured lockout after settling, got %s", got)
+		}
+	})
+
+	t.Run("no effect without detection", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithLockOut(time.Second), WithFlapDampening(time.Minute, 0))
+		for i := 0; i < 3; i++ {
+			cycle(b, Open)
+			if got := lockoutOf(t, b); got != time.Second {
+				t.Fatalf("expected the configured lockout, got %s", got)
+			}
+			cycle(b, Throttled)
+		}
+	})
+}
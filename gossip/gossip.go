@@ -0,0 +1,243 @@
+// Package gossip shares breaker state between processes peer-to-peer over
+// UDP, without an external store.
+//
+// A Node implements circuit.StateStore: every state a breaker publishes is
+// broadcast as a single datagram to the configured peers, and states
+// received from peers are kept in memory. Breakers configured with
+// circuit.WithStateStore(node) therefore treat remote "open" signals as
+// hints, combined with the breaker's circuit.MergePolicy. Delivery is best
+// effort; a lost datagram is superseded by the next publish, a late one
+// is dropped if a newer state from the same peer has arrived, and replicas
+// that stop publishing are ignored once their state goes stale.
+package gossip
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+// maxDatagram bounds the size of received messages.
+const maxDatagram = 64 * 1024
+
+// Option configures a Node.
+type Option func(*Node) error
+
+// WithPeers sets the UDP addresses (host:port) that states are broadcast to.
+func WithPeers(addrs ...string) Option {
+	return func(n *Node) error {
+		for _, addr := range addrs {
+			if err := n.addPeer(addr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithKey authenticates messages with HMAC-SHA256 using a key shared by
+// all peers. Messages without a valid signature are dropped.
+// Without a key, any host that can reach the node can inject state.
+func WithKey(key []byte) Option {
+	return func(n *Node) error {
+		n.key = append([]byte(nil), key...)
+		return nil
+	}
+}
+
+// WithOnError sets a callback for errors in the receive loop, such as
+// undecodable or unauthenticated messages. By default they are dropped silently.
+func WithOnError(fn func(error)) Option {
+	return func(n *Node) error {
+		n.onError = fn
+		return nil
+	}
+}
+
+// Node is a gossip participant. It is safe for concurrent use.
+type Node struct {
+	conn    *net.UDPConn
+	key     []byte
+	onError func(error)
+	states  *circuit.MemoryStateStore
+
+	peersMu sync.RWMutex
+	peers   []*net.UDPAddr
+
+	done chan struct{}
+}
+
+var _ circuit.StateStore = (*Node)(nil)
+
+// Listen binds a UDP socket on addr (e.g. ":7946" or "127.0.0.1:0") and
+// starts a single goroutine that receives states from peers.
+// Call Close to stop it.
+func Listen(addr string, opts ...Option) (*Node, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("gossip: invalid listen address %q: %w", addr, err)
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("gossip: unable to listen on %q: %w", addr, err)
+	}
+
+	n := &Node{
+		conn:   conn,
+		states: circuit.NewMemoryStateStore(),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(n); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	go n.receive()
+	return n, nil
+}
+
+// Addr returns the address the node is listening on.
+func (n *Node) Addr() net.Addr {
+	return n.conn.LocalAddr()
+}
+
+// AddPeer adds a UDP address (host:port) that states are broadcast to.
+func (n *Node) AddPeer(addr string) error {
+	return n.addPeer(addr)
+}
+
+func (n *Node) addPeer(addr string) error {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return fmt.Errorf("gossip: invalid peer address %q: %w", addr, err)
+	}
+	n.peersMu.Lock()
+	n.peers = append(n.peers, udpAddr)
+	n.peersMu.Unlock()
+	return nil
+}
+
+// Publish records the state locally and broadcasts it to every peer.
+// Send errors are joined and returned, but do not stop the broadcast.
+func (n *Node) Publish(ctx context.Context, state circuit.PeerState) error {
+	if err := n.states.Publish(ctx, state); err != nil {
+		return err
+	}
+
+	msg, err := n.encode(state)
+	if err != nil {
+		return err
+	}
+
+	n.peersMu.RLock()
+	peers := n.peers
+	n.peersMu.RUnlock()
+
+	var errs []error
+	for _, peer := range peers {
+		if _, err := n.conn.WriteToUDP(msg, peer); err != nil {
+			errs = append(errs, fmt.Errorf("gossip: unable to send to %s: %w", peer, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Peers returns the latest state received from every peer, and
+// published locally, for the named breaker.
+func (n *Node) Peers(ctx context.Context, breaker string) ([]circuit.PeerState, error) {
+	return n.states.Peers(ctx, breaker)
+}
+
+// Close stops the receive loop and closes the socket.
+func (n *Node) Close() error {
+	err := n.conn.Close()
+	<-n.done
+	return err
+}
+
+// receive stores states from peers until the socket is closed.
+// Datagrams may arrive out of order, so a state is dropped unless it is
+// newer than the last one received from the same peer for that breaker.
+func (n *Node) receive() {
+	defer close(n.done)
+
+	type source struct{ breaker, peer string }
+	latest := make(map[source]time.Time)
+
+	buf := make([]byte, maxDatagram)
+	for {
+		sz, from, err := n.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			n.reportError(err)
+			continue
+		}
+
+		state, err := n.decode(buf[:sz])
+		if err != nil {
+			n.reportError(fmt.Errorf("gossip: dropped message from %s: %w", from, err))
+			continue
+		}
+		src := source{state.Breaker, state.Peer}
+		if last, ok := latest[src]; ok && !state.Updated.After(last) {
+			continue
+		}
+		latest[src] = state.Updated
+		_ = n.states.Publish(context.Background(), state)
+	}
+}
+
+func (n *Node) reportError(err error) {
+	if n.onError != nil {
+		n.onError(err)
+	}
+}
+
+// encode serialises a state, prefixed with its signature if a key is set.
+func (n *Node) encode(state circuit.PeerState) ([]byte, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if n.key == nil {
+		return payload, nil
+	}
+	mac := hmac.New(sha256.New, n.key)
+	mac.Write(payload)
+	return append(mac.Sum(nil), payload...), nil
+}
+
+// decode verifies the signature, if a key is set, and deserialises a state.
+func (n *Node) decode(msg []byte) (circuit.PeerState, error) {
+	var state circuit.PeerState
+	if n.key != nil {
+		if len(msg) < sha256.Size {
+			return state, errors.New("message too short to be signed")
+		}
+		mac := hmac.New(sha256.New, n.key)
+		mac.Write(msg[sha256.Size:])
+		if !hmac.Equal(mac.Sum(nil), msg[:sha256.Size]) {
+			return state, errors.New("invalid signature")
+		}
+		msg = msg[sha256.Size:]
+	}
+	if err := json.Unmarshal(msg, &state); err != nil {
+		return state, err
+	}
+	if state.Breaker == "" || state.Peer == "" {
+		return state, errors.New("missing breaker or peer name")
+	}
+	return state, nil
+}
@@ -0,0 +1,209 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+func mustListen(t *testing.T, opts ...Option) *Node {
+	t.Helper()
+	n, err := Listen("127.0.0.1:0", opts...)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { n.Close() })
+	return n
+}
+
+// eventually polls cond until it is true or the timeout elapses.
+func eventually(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNode(t *testing.T) {
+	t.Parallel()
+
+	t.Run("broadcasts to peers", func(t *testing.T) {
+		t.Parallel()
+		a, b, c := mustListen(t), mustListen(t), mustListen(t)
+		for _, n := range []*Node{a, b, c} {
+			for _, peer := range []*Node{a, b, c} {
+				if n != peer {
+					n.AddPeer(peer.Addr().String())
+				}
+			}
+		}
+
+		ctx := context.Background()
+		state := circuit.PeerState{Breaker: "api", Peer: "a", State: circuit.Open, Errors: 2, Updated: time.Now()}
+		if err := a.Publish(ctx, state); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, n := range []*Node{a, b, c} {
+			eventually(t, time.Second, func() bool {
+				peers, _ := n.Peers(ctx, "api")
+				return len(peers) == 1 && peers[0].State == circuit.Open && peers[0].Errors == 2
+			})
+		}
+	})
+
+	t.Run("signed messages", func(t *testing.T) {
+		t.Parallel()
+		key := []byte("shared-secret")
+		var mu sync.Mutex
+		var dropped []error
+		receiver := mustListen(t, WithKey(key), WithOnError(func(err error) {
+			mu.Lock()
+			dropped = append(dropped, err)
+			mu.Unlock()
+		}))
+		signed := mustListen(t, WithKey(key), WithPeers(receiver.Addr().String()))
+		forged := mustListen(t, WithKey([]byte("wrong")), WithPeers(receiver.Addr().String()))
+		unsigned := mustListen(t, WithPeers(receiver.Addr().String()))
+
+		ctx := context.Background()
+		forged.Publish(ctx, circuit.PeerState{Breaker: "api", Peer: "forged", State: circuit.Open})
+		unsigned.Publish(ctx, circuit.PeerState{Breaker: "api", Peer: "unsigned", State: circuit.Open})
+		signed.Publish(ctx, circuit.PeerState{Breaker: "api", Peer: "signed", State: circuit.Open})
+
+		eventually(t, time.Second, func() bool {
+			peers, _ := receiver.Peers(ctx, "api")
+			return len(peers) == 1
+		})
+		eventually(t, time.Second, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(dropped) == 2
+		})
+		peers, _ := receiver.Peers(ctx, "api")
+		if peers[0].Peer != "signed" {
+			t.Fatalf("expected only the signed state, got %+v", peers)
+		}
+	})
+
+	t.Run("drops late states", func(t *testing.T) {
+		t.Parallel()
+		receiver := mustListen(t)
+		sender := mustListen(t, WithPeers(receiver.Addr().String()))
+
+		ctx := context.Background()
+		now := time.Now()
+		sender.Publish(ctx, circuit.PeerState{Breaker: "api", Peer: "a", State: circuit.Closed, Updated: now})
+		// an earlier state delivered late must not roll the peer back
+		sender.Publish(ctx, circuit.PeerState{Breaker: "api", Peer: "a", State: circuit.Open, Updated: now.Add(-time.Second)})
+		sender.Publish(ctx, circuit.PeerState{Breaker: "marker", Peer: "a", State: circuit.Closed, Updated: now})
+
+		eventually(t, time.Second, func() bool {
+			peers, _ := receiver.Peers(ctx, "marker")
+			return len(peers) == 1
+		})
+		if peers, _ := receiver.Peers(ctx, "api"); len(peers) != 1 || peers[0].State != circuit.Closed {
+			t.Fatalf("expected the newer closed state to be kept, got %+v", peers)
+		}
+	})
+
+	t.Run("drops malformed messages", func(t *testing.T) {
+		t.Parallel()
+		errs := make(chan error, 2)
+		n := mustListen(t, WithOnError(func(err error) { errs <- err }))
+
+		conn, err := net.Dial("udp", n.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("not json"))
+		conn.Write([]byte(`{"state":"open"}`))
+
+		for i := 0; i < 2; i++ {
+			select {
+			case <-errs:
+			case <-time.After(time.Second):
+				t.Fatal("expected malformed message to be reported")
+			}
+		}
+	})
+
+	t.Run("invalid addresses", func(t *testing.T) {
+		t.Parallel()
+		if _, err := Listen("not an address"); err == nil {
+			t.Fatal("expected listen error")
+		}
+		if _, err := Listen("127.0.0.1:0", WithPeers("nope:nope")); err == nil {
+			t.Fatal("expected peer error")
+		}
+	})
+
+	t.Run("close stops receiving", func(t *testing.T) {
+		t.Parallel()
+		n, err := Listen("127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen failed: %v", err)
+		}
+		if err := n.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestBreakerBoxes(t *testing.T) {
+	t.Parallel()
+
+	// three boxes, each with its own node, all peered on loopback
+	nodes := []*Node{mustListen(t), mustListen(t), mustListen(t)}
+	for _, n := range nodes {
+		for _, peer := range nodes {
+			if n != peer {
+				n.AddPeer(peer.Addr().String())
+			}
+		}
+	}
+
+	boxes := make([]*circuit.BreakerBox, len(nodes))
+	for i, n := range nodes {
+		boxes[i] = circuit.NewBreakerBox()
+		_, err := boxes[i].Create(
+			circuit.WithName("payments"),
+			circuit.WithLockOut(time.Second),
+			circuit.WithStateStore(n),
+			circuit.WithPeerID(n.Addr().String()),
+			circuit.WithSyncInterval(10*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	failing := boxes[0].Load("payments")
+	circuit.Run(failing, ctx, func(ctx context.Context) (int, error) {
+		return 0, errors.New("boom")
+	})
+	failing.Allow(ctx) // closed -> open
+	failing.Allow(ctx) // broadcast open
+
+	for _, bb := range boxes[1:] {
+		b := bb.Load("payments")
+		eventually(t, time.Second, func() bool {
+			_, err := b.Allow(ctx)
+			return errors.Is(err, circuit.ErrStateOpen)
+		})
+		if b.State() != circuit.Closed {
+			t.Fatalf("expected local state to stay Closed, got %s", b.State())
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package circuit
+
+import "time"
+
+// DefaultHistorySize is the default number of transitions kept by each breaker.
+const DefaultHistorySize = 32
+
+// reasons recorded with each Transition
+const (
+	reasonThresholdExceeded = "error threshold exceeded"
+	reasonRecovering        = "errors within threshold"
+	reasonBackOffElapsed    = "backoff elapsed"
+	reasonRampedUp          = "ramp-up complete"
+	reasonForcedOpen        = "forced open"
+	reasonForcedClosed      = "forced closed"
+	reasonReset             = "reset"
+	reasonRestored          = "restored"
+)
+
+// Transition is a state change recorded in a breaker's history.
+//
+// Reason is one of "error threshold exceeded" (closed or throttled to
+// open), "errors within threshold" (open to throttled, once any lockout
+// has ended), "backoff elapsed" (throttled to closed), "ramp-up complete"
+// (throttled to closed, with WithRampUp), "forced open", "forced closed",
+// "reset" or "restored".
+type Transition struct {
+	From   State     `json:"from"`
+	To     State     `json:"to"`
+	At     time.Time `json:"at"`
+	Errors int       `json:"errors"` // errors in the window at the time of the transition
+	Reason string    `json:"reason"`
+}
+
+// history is a fixed-size ring buffer of transitions.
+// It is protected by the breaker's stateMX.
+type history struct {
+	buf  []Transition
+	next int
+	full bool
+}
+
+func newHistory(size int) *history {
+	return &history{buf: make([]Transition, size)}
+}
+
+func (h *history) add(t Transition) {
+	h.buf[h.next] = t
+	h.next++
+	if h.next == len(h.buf) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// list returns the recorded transitions, oldest first.
+func (h *history) list() []Transition {
+	if !h.full {
+		return append([]Transition(nil), h.buf[:h.next]...)
+	}
+	out := make([]Transition, 0, len(h.buf))
+	out = append(out, h.buf[h.next:]...)
+	return append(out, h.buf[:h.next]...)
+}
+
+// History returns the breaker's most recent transitions, oldest first.
+// At most the number set with WithHistorySize are kept.
+func (b *Breaker) History() []Transition {
+	b.stateMX.Lock()
+	defer b.stateMX.Unlock()
+	if b.history == nil {
+		return nil
+	}
+	return b.history.list()
+}
@@ -0,0 +1,138 @@
+package circuit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ring buffer", func(t *testing.T) {
+		t.Parallel()
+		h := newHistory(3)
+		if got := h.list(); len(got) != 0 {
+			t.Fatalf("expected empty history, got %v", got)
+		}
+		for i := 1; i <= 5; i++ {
+			h.add(Transition{Errors: i})
+			want := min(i, 3)
+			got := h.list()
+			if len(got) != want {
+				t.Fatalf("after %d adds: expected %d transitions, got %d", i, want, len(got))
+			}
+			for j, tr := range got {
+				if tr.Errors != i-want+j+1 {
+					t.Fatalf("after %d adds: expected oldest first, got %v", i, got)
+				}
+			}
+		}
+	})
+
+	t.Run("records transitions with reasons", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t,
+			WithBackOff(time.Millisecond),
+			WithWindow(20*time.Millisecond),
+		)
+		if h := b.History(); len(h) != 0 {
+			t.Fatalf("expected no history for a new breaker, got %v", h)
+		}
+
+		done, _ := b.Allow(context.Background())
+		done(context.DeadlineExceeded)
+		b.State()                         // closed -> open
+		time.Sleep(60 * time.Millisecond) // past the window and the tracker's eviction interval
+		b.State()                         // open -> throttled
+		time.Sleep(15 * time.Millisecond)
+		b.State() // throttled -> closed
+		b.ForceOpen()
+		b.ForceClose()
+		b.Reset() // already closed, no transition
+
+		want := []Transition{
+			{From: Closed, To: Open, Errors: 1, Reason: "error threshold exceeded"},
+			{From: Open, To: Throttled, Errors: 0, Reason: "errors within threshold"},
+			{From: Throttled, To: Closed, Errors: 0, Reason: "backoff elapsed"},
+			{From: Closed, To: Open, Errors: 0, Reason: "forced open"},
+			{From: Open, To: Closed, Errors: 0, Reason: "forced closed"},
+		}
+		got := b.History()
+		if len(got) != len(want) {
+			t.Fatalf("expected %d transitions, got %+v", len(want), got)
+		}
+		for i, tr := range got {
+			if tr.At.IsZero() || (i > 0 && tr.At.Before(got[i-1].At)) {
+				t.Fatalf("transition %d: expected ordered timestamps, got %+v", i, got)
+			}
+			tr.At = time.Time{}
+			if tr != want[i] {
+				t.Fatalf("transition %d: expected %+v, got %+v", i, want[i], tr)
+			}
+		}
+	})
+
+	t.Run("errors counted before opening resets them", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(1), WithOpeningResetsErrors(true))
+		for i := 0; i < 2; i++ {
+			done, _ := b.Allow(context.Background())
+			done(context.DeadlineExceeded)
+		}
+		b.State()
+		if h := b.History(); len(h) != 1 || h[0].Errors != 2 {
+			t.Fatalf("expected one transition with 2 errors, got %+v", h)
+		}
+	})
+
+	t.Run("restore and reset", func(t *testing.T) {
+		t.Parallel()
+		src := mustNewBreaker(t, WithName("restored"))
+		src.ForceOpen()
+		b := mustNewBreaker(t, WithName("restored"))
+		if err := b.Restore(src.Export()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b.Reset()
+
+		h := b.History()
+		if len(h) != 2 || h[0].Reason != "restored" || h[0].To != Open || h[1].Reason != "reset" {
+			t.Fatalf("unexpected history: %+v", h)
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithHistorySize(2))
+		b.ForceOpen()
+		b.ForceClose()
+		b.ForceOpen()
+		h := b.History()
+		if len(h) != 2 || h[0].Reason != "forced closed" || h[1].Reason != "forced open" {
+			t.Fatalf("expected the 2 most recent transitions, got %+v", h)
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithName("snap-history"))
+		b.ForceOpen()
+		if b.Snapshot().History != nil {
+			t.Fatal("expected no history by default")
+		}
+
+		data, err := json.Marshal(b.Snapshot(IncludeHistory()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var decoded BreakerState
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(decoded.History) != 1 || decoded.History[0].From != Closed || decoded.History[0].To != Open || decoded.History[0].Reason != "forced open" {
+			t.Fatalf("unexpected history in JSON %s", data)
+		}
+	})
+}
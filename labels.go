@@ -0,0 +1,37 @@
+package circuit
+
+import (
+	"context"
+	"time"
+)
+
+// Labels are the dimensions of a call, such as its tenant, route or
+// caller, extracted from its context by the function set with WithLabels.
+type Labels map[string]string
+
+// LabelFunc extracts the labels of a call from its context.
+type LabelFunc func(ctx context.Context) Labels
+
+// LabeledMetricsCollector is an optional extension of MetricsCollector.
+// If the collector passed to WithMetrics also implements
+// LabeledMetricsCollector, calls are recorded with these methods instead
+// of their MetricsCollector counterparts, along with the labels extracted
+// from the context passed to Run or Allow. The labels are nil if no
+// extractor is set with WithLabels. State changes are not tied to a call,
+// so they are always recorded with RecordStateChange.
+// All methods must be safe for concurrent use, and must not modify labels.
+type LabeledMetricsCollector interface {
+	RecordSuccessWithLabels(breakerName string, duration time.Duration, labels Labels)
+	RecordErrorWithLabels(breakerName string, duration time.Duration, err error, labels Labels)
+	RecordTimeoutWithLabels(breakerName string, labels Labels)
+	RecordRejectedWithLabels(breakerName string, state State, labels Labels)
+	RecordExcludedWithLabels(breakerName string, err error, labels Labels)
+}
+
+// labels returns the labels of the call made with ctx.
+func (b *Breaker) labels(ctx context.Context) Labels {
+	if b.labelsFunc == nil {
+		return nil
+	}
+	return b.labelsFunc(ctx)
+}
@@ -0,0 +1,183 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type tenantKey struct{}
+
+// tenantLabels labels calls with the tenant stored in their context.
+func tenantLabels(ctx context.Context) Labels {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return Labels{"tenant": tenant}
+	}
+	return nil
+}
+
+// labeledMetrics records every call, labeled or not, as a string.
+type labeledMetrics struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (m *labeledMetrics) add(format string, args ...any) {
+	m.mu.Lock()
+	m.calls = append(m.calls, fmt.Sprintf(format, args...))
+	m.mu.Unlock()
+}
+
+func (m *labeledMetrics) list() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls...)
+}
+
+func (m *labeledMetrics) RecordSuccess(name string, _ time.Duration) {
+	m.add("success %s", name)
+}
+
+func (m *labeledMetrics) RecordError(name string, _ time.Duration, _ error) {
+	m.add("error %s", name)
+}
+
+func (m *labeledMetrics) RecordTimeout(name string) {
+	m.add("timeout %s", name)
+}
+
+func (m *labeledMetrics) RecordStateChange(name string, _, to State) {
+	m.add("state %s %s", name, to)
+}
+
+func (m *labeledMetrics) RecordRejected(name string, state State) {
+	m.add("rejected %s %s", name, state)
+}
+
+func (m *labeledMetrics) RecordExcluded(name string, _ error) {
+	m.add("excluded %s", name)
+}
+
+func (m *labeledMetrics) RecordSuccessWithLabels(name string, _ time.Duration, l Labels) {
+	m.add("success %s %v", name, l)
+}
+
+func (m *labeledMetrics) RecordErrorWithLabels(name string, _ time.Duration, _ error, l Labels) {
+	m.add("error %s %v", name, l)
+}
+
+func (m *labeledMetrics) RecordTimeoutWithLabels(name string, l Labels) {
+	m.add("timeout %s %v", name, l)
+}
+
+func (m *labeledMetrics) RecordRejectedWithLabels(name string, state State, l Labels) {
+	m.add("rejected %s %s %v", name, state, l)
+}
+
+func (m *labeledMetrics) RecordExcludedWithLabels(name string, _ error, l Labels) {
+	m.add("excluded %s %v", name, l)
+}
+
+func TestLabeledMetrics(t *testing.T) {
+	t.Parallel()
+
+	excluded := errors.New("excluded")
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	succeed := func(context.Context) (int, error) { return 0, nil }
+
+	same := func(t *testing.T, got []string, want ...string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("expected %q, got %q", want, got)
+			}
+		}
+	}
+
+	t.Run("records labels", func(t *testing.T) {
+		t.Parallel()
+		m := &labeledMetrics{}
+		b := mustNewBreaker(t,
+			WithName("l1"),
+			WithMetrics(m),
+			WithLabels(tenantLabels),
+			WithTimeout(time.Millisecond),
+			WithThreshold(1),
+			WithIsExcluded(func(err error) bool { return errors.Is(err, excluded) }),
+		)
+		_, _ = Run(b, ctx, succeed)
+		_, _ = Run(b, ctx, func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		_, _ = Run(b, ctx, func(context.Context) (int, error) { return 0, excluded })
+		done, _ := b.Allow(context.Background())
+		done(errors.New("boom"))
+		_, _ = Run(b, ctx, succeed)
+
+		same(t, m.list(),
+			"success l1 map[tenant:acme]",
+			"timeout l1 map[tenant:acme]",
+			"error l1 map[tenant:acme]",
+			"excluded l1 map[tenant:acme]",
+			"error l1 map[]",
+			"state l1 open",
+			"rejected l1 open map[tenant:acme]",
+		)
+	})
+
+	t.Run("without an extractor", func(t *testing.T) {
+		t.Parallel()
+		m := &labeledMetrics{}
+		b := mustNewBreaker(t, WithName("l2"), WithMetrics(m))
+		_, _ = Run(b, ctx, succeed)
+		same(t, m.list(), "success l2 map[]")
+	})
+
+	t.Run("falls back for plain collectors", func(t *testing.T) {
+		t.Parallel()
+		m := &mockMetrics{}
+		extracted := false
+		b := mustNewBreaker(t, WithMetrics(m), WithLabels(func(context.Context) Labels {
+			extracted = true
+			return nil
+		}))
+		_, _ = Run(b, ctx, succeed)
+		_, _ = Run(b, ctx, func(context.Context) (int, error) { return 0, errors.New("boom") })
+		_, _ = Run(b, ctx, succeed)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.successes != 1 || m.errors != 1 || m.rejected != 1 {
+			t.Fatalf("expected plain calls, got %+v", m)
+		}
+		if extracted {
+			t.Fatal("expected no labels to be extracted")
+		}
+	})
+
+	t.Run("parents use their own labels", func(t *testing.T) {
+		t.Parallel()
+		pm, cm := &labeledMetrics{}, &labeledMetrics{}
+		parent := mustNewBreaker(t, WithName("parent"), WithMetrics(pm), WithLabels(func(context.Context) Labels {
+			return Labels{"tier": "db"}
+		}))
+		child := mustNewBreaker(t, WithName("child"), WithParent(parent), WithMetrics(cm), WithLabels(tenantLabels))
+
+		_, _ = Run(child, ctx, func(context.Context) (int, error) { return 0, errors.New("boom") })
+		_, _ = Run(child, ctx, succeed)
+
+		same(t, pm.list(),
+			"error parent map[tier:db]",
+			"state parent open",
+			"rejected parent open map[tier:db]",
+		)
+		// the parent rejects before the child evaluates its own state
+		same(t, cm.list(), "error child map[tenant:acme]", "rejected child open map[tenant:acme]")
+	})
+}
@@ -0,0 +1,79 @@
+package circuit
+
+import (
+	"math"
+	"time"
+)
+
+// LockoutPolicy escalates the lockout each time a throttled breaker
+// reopens, so a dependency that stays down is probed less and less often.
+// The escalation resets when the breaker closes.
+//
+// The lockout for the nth consecutive reopen is Base * Multiplier^n,
+// capped at Max, then randomised by up to ±Jitter so that replicas do not
+// probe a recovering dependency in lockstep. The lockout in effect is
+// reported in BreakerState.LockoutEnds.
+type LockoutPolicy struct {
+	// Base is the lockout after the breaker first opens.
+	// If zero, the lockout set with WithLockOut is used.
+	Base time.Duration
+	// Multiplier is applied for each consecutive reopen. The default is 2.
+	Multiplier float64
+	// Max caps the lockout, including jitter. If zero, it is unbounded.
+	Max time.Duration
+	// Jitter is the fraction, in [0, 1], by which each lockout is randomly
+	// lengthened or shortened. For example, 0.1 is ±10%.
+	Jitter float64
+}
+
+// DefaultLockoutMultiplier is the Multiplier used when a LockoutPolicy leaves it unset.
+const DefaultLockoutMultiplier = 2.0
+
+// escalate returns the lockout after n consecutive reopens, before jitter.
+func (p LockoutPolicy) escalate(base time.Duration, n uint) time.Duration {
+	m := p.Multiplier
+	if m == 0 {
+		m = DefaultLockoutMultiplier
+	}
+	return p.clamp(float64(base) * math.Pow(m, float64(n)))
+}
+
+// jitter randomises d by up to ±Jitter, without exceeding Max.
+// random returns a number in [0, 1).
+func (p LockoutPolicy) jitter(d time.Duration, random func() float64) time.Duration {
+	if p.Jitter == 0 {
+		return d
+	}
+	return p.clamp(float64(d) * (1 + p.Jitter*(2*random()-1)))
+}
+
+func (p LockoutPolicy) clamp(d float64) time.Duration {
+	if p.Max > 0 && d > float64(p.Max) {
+		return p.Max
+	}
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// nextLockout returns the lockout for a transition to Open: the
+// configured lockout, escalated by the lockout policy, lengthened by
+// flap dampening and randomised by the policy's jitter.
+// Must be called with stateMX held, after the reopen has been counted.
+func (b *Breaker) nextLockout() time.Duration {
+	p := b.lockoutPolicy
+	if p == nil {
+		return b.dampen(b.lockout)
+	}
+
+	base := b.lockout
+	if p.Base > 0 {
+		base = p.Base
+	}
+	d := b.dampen(p.escalate(base, b.reopens))
+	if d == 0 {
+		return 0
+	}
+	return p.jitter(d, b.float64)
+}
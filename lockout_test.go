@@ -0,0 +1,125 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("escalates on reopen and resets on close", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t,
+			WithBackOff(10*time.Millisecond),
+			WithLockoutPolicy(LockoutPolicy{Base: time.Second, Multiplier: 3, Max: 10 * time.Second}),
+		)
+
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != time.Second {
+			t.Fatalf("expected the base lockout, got %s", got)
+		}
+		for _, want := range []time.Duration{3 * time.Second, 9 * time.Second, 10 * time.Second} {
+			cycle(b, Throttled)
+			cycle(b, Open)
+			if got := lockoutOf(t, b); got != want {
+				t.Fatalf("expected an escalated lockout of %s, got %s", want, got)
+			}
+		}
+
+		cycle(b, Throttled)
+		time.Sleep(15 * time.Millisecond)
+		if b.State() != Closed {
+			t.Fatal("expected breaker to close after the backoff")
+		}
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != time.Second {
+			t.Fatalf("expected the base lockout after closing, got %s", got)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithLockOut(time.Second), WithLockoutPolicy(LockoutPolicy{}))
+		cycle(b, Open)
+		cycle(b, Throttled)
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != 2*time.Second {
+			t.Fatalf("expected WithLockOut doubled, got %s", got)
+		}
+	})
+
+	t.Run("fixed lockout without a policy", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithLockOut(time.Second))
+		for i := 0; i < 3; i++ {
+			cycle(b, Open)
+			if got := lockoutOf(t, b); got != time.Second {
+				t.Fatalf("expected a fixed lockout, got %s", got)
+			}
+			cycle(b, Throttled)
+		}
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithLockoutPolicy(LockoutPolicy{
+			Base:       time.Second,
+			Multiplier: 1,
+			Max:        1200 * time.Millisecond,
+			Jitter:     0.5,
+		}))
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 20; i++ {
+			cycle(b, Open)
+			got := lockoutOf(t, b)
+			if got < 500*time.Millisecond || got > 1200*time.Millisecond {
+				t.Fatalf("expected lockout within jitter and max, got %s", got)
+			}
+			seen[got] = true
+			cycle(b, Throttled)
+		}
+		if len(seen) < 2 {
+			t.Fatal("expected jitter to vary the lockout")
+		}
+	})
+
+	t.Run("reconfigure applies on next open", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithLockOut(time.Second))
+		cycle(b, Open)
+		if err := b.Reconfigure(WithLockoutPolicy(LockoutPolicy{Multiplier: 4})); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := lockoutOf(t, b); got != time.Second {
+			t.Fatalf("expected the current lock to be unchanged, got %s", got)
+		}
+		cycle(b, Throttled)
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != 4*time.Second {
+			t.Fatalf("expected the new policy on reopen, got %s", got)
+		}
+	})
+
+	t.Run("export and restore", func(t *testing.T) {
+		t.Parallel()
+		policy := WithLockoutPolicy(LockoutPolicy{Base: time.Second})
+		src := mustNewBreaker(t, WithName("escalated"), policy)
+		cycle(src, Open)
+		cycle(src, Throttled)
+		cycle(src, Open)
+
+		b := mustNewBreaker(t, WithName("escalated"), policy)
+		if err := b.Restore(src.Export()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := lockoutOf(t, b); got != 2*time.Second {
+			t.Fatalf("expected the restored lockout, got %s", got)
+		}
+		cycle(b, Throttled)
+		cycle(b, Open)
+		if got := lockoutOf(t, b); got != 4*time.Second {
+			t.Fatalf("expected escalation to continue, got %s", got)
+		}
+	})
+}
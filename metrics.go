@@ -1,6 +1,10 @@
 package circuit
 
-import "time"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 // MetricsCollector is an optional interface for collecting circuit breaker metrics.
 // Implement this interface and pass it via WithMetrics to instrument a breaker.
@@ -28,3 +32,57 @@ type MetricsCollector interface {
 	// via IsExcluded. Excluded errors are not tracked as successes or failures.
 	RecordExcluded(breakerName string, err error)
 }
+
+// The record functions count the outcome of a call made with ctx in the
+// rolling stats, if enabled, and report it to the metrics collector.
+
+func (b *Breaker) recordSuccess(ctx context.Context, elapsed time.Duration) {
+	b.recordStat(statSuccess, elapsed)
+	if lm, ok := b.metrics.(LabeledMetricsCollector); ok {
+		lm.RecordSuccessWithLabels(b.name, elapsed, b.labels(ctx))
+	} else if b.metrics != nil {
+		b.metrics.RecordSuccess(b.name, elapsed)
+	}
+}
+
+func (b *Breaker) recordError(ctx context.Context, elapsed time.Duration, err error) {
+	b.recordStat(statFailure, elapsed)
+	if lm, ok := b.metrics.(LabeledMetricsCollector); ok {
+		lm.RecordErrorWithLabels(b.name, elapsed, err, b.labels(ctx))
+	} else if b.metrics != nil {
+		b.metrics.RecordError(b.name, elapsed, err)
+	}
+}
+
+func (b *Breaker) recordTimeout(ctx context.Context) {
+	b.recordStat(statTimeout, 0)
+	if lm, ok := b.metrics.(LabeledMetricsCollector); ok {
+		lm.RecordTimeoutWithLabels(b.name, b.labels(ctx))
+	} else if b.metrics != nil {
+		b.metrics.RecordTimeout(b.name)
+	}
+}
+
+func (b *Breaker) recordExcluded(ctx context.Context, err error) {
+	b.recordStat(statExcluded, 0)
+	if lm, ok := b.metrics.(LabeledMetricsCollector); ok {
+		lm.RecordExcludedWithLabels(b.name, err, b.labels(ctx))
+	} else if b.metrics != nil {
+		b.metrics.RecordExcluded(b.name, err)
+	}
+}
+
+// recordRejected records a request rejected with err, if err is a
+// rejection by a breaker rather than, e.g., a cancelled context.
+func (b *Breaker) recordRejected(ctx context.Context, err error) {
+	var circErr Error
+	if !errors.As(err, &circErr) {
+		return
+	}
+	b.recordStat(statRejected, 0)
+	if lm, ok := b.metrics.(LabeledMetricsCollector); ok {
+		lm.RecordRejectedWithLabels(b.name, circErr.State, b.labels(ctx))
+	} else if b.metrics != nil {
+		b.metrics.RecordRejected(b.name, circErr.State)
+	}
+}
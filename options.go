@@ -1,6 +1,12 @@
 package circuit
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
 
 // Option configures a Breaker. Use the With* functions to create Options.
 type Option func(*Breaker)
@@ -23,7 +29,8 @@ func WithTimeout(d time.Duration) Option {
 }
 
 // WithBackOff sets the duration that a circuit breaker is throttled.
-// The default is 1 minute. The minimum is 10ms.
+// The default is 1 minute. The minimum is 10ms; shorter values are
+// raised to the minimum unless strict mode is enabled.
 func WithBackOff(d time.Duration) Option {
 	return func(b *Breaker) {
 		b.backoff = d
@@ -31,7 +38,8 @@ func WithBackOff(d time.Duration) Option {
 }
 
 // WithWindow sets the length of time checked for error calculation.
-// The default is 5 minutes. The minimum is 10ms.
+// The default is 5 minutes. The minimum is 10ms; shorter values are
+// raised to the minimum unless strict mode is enabled.
 func WithWindow(d time.Duration) Option {
 	return func(b *Breaker) {
 		b.window = d
@@ -51,18 +59,90 @@ func WithThreshold(n uint32) Option {
 // forced open before attempting to throttle. If no lockout is
 // provided, the circuit breaker will transition to a throttled
 // state only after its error count is at or below the threshold.
+// The lockout must not be longer than the error window.
 func WithLockOut(d time.Duration) Option {
 	return func(b *Breaker) {
 		b.lockout = d
 	}
 }
 
+// WithLockoutPolicy escalates the lockout each time the breaker reopens
+// from the throttled state, until it closes. See LockoutPolicy.
+func WithLockoutPolicy(p LockoutPolicy) Option {
+	return func(b *Breaker) {
+		b.lockoutPolicy = &p
+	}
+}
+
+// WithRand sets the source of randomness for throttling decisions and
+// lockout jitter, so that a breaker's behaviour can be replayed exactly,
+// e.g. with rand.NewPCG and a fixed seed. It is not used by estimation
+// or probability functions: curves built with Jitter or JitterProbability
+// draw from the source passed to them, and JitteredLinear and
+// JitteredLinearProbability from the global one. The source is only used
+// while the breaker holds its internal lock, so it does not need
+// to be safe for concurrent use, but it must not be shared with other
+// breakers. By default, the global source in math/rand/v2 is used.
+func WithRand(src rand.Source) Option {
+	return func(b *Breaker) {
+		b.rng = nil
+		if src != nil {
+			b.rng = rand.New(src)
+		}
+	}
+}
+
+// WithClock sets the clock used for the breaker's lockout, backoff, error
+// window and timestamps, e.g. to drive it through simulated time.
+// By default, the system clock is used. See Clock.
+func WithClock(c Clock) Option {
+	return func(b *Breaker) {
+		b.clock = c
+	}
+}
+
+// WithRampUp replaces the time-based backoff with success-driven recovery.
+// On entering the throttled state the breaker admits step percent of
+// requests; each success raises that by step and each failure halves it,
+// and the breaker closes once every request is admitted. Errors still
+// count towards the threshold, so a failing dependency reopens the breaker.
+// The step must be at most 100; zero, the default, disables ramp-up.
+func WithRampUp(step uint32) Option {
+	return func(b *Breaker) {
+		b.rampStep = step
+	}
+}
+
 // WithEstimationFunc sets the function used to determine the chance
 // of a request being throttled during the backoff period.
 // By default, Linear estimation is used.
+// It replaces any function set with WithProbabilityFunc.
 func WithEstimationFunc(f EstimationFunc) Option {
+	return withEstimation(builtinName(builtinEstimations, f), f)
+}
+
+// withEstimation sets f as the EstimationFunc, reported by Config as name.
+func withEstimation(name string, f EstimationFunc) Option {
 	return func(b *Breaker) {
 		b.estimate = f
+		b.probability = nil
+		b.estimationName = name
+	}
+}
+
+// WithProbabilityFunc sets a higher-resolution function used to determine
+// the chance of a request being throttled during the backoff period.
+// See ProbabilityFunc. It replaces any function set with WithEstimationFunc.
+func WithProbabilityFunc(f ProbabilityFunc) Option {
+	return withProbability(builtinName(builtinProbabilities, f), f)
+}
+
+// withProbability sets f as the ProbabilityFunc, reported by Config as name.
+func withProbability(name string, f ProbabilityFunc) Option {
+	return func(b *Breaker) {
+		b.probability = f
+		b.estimate = nil
+		b.estimationName = name
 	}
 }
 
@@ -75,6 +155,120 @@ func WithOpeningResetsErrors(v bool) Option {
 	}
 }
 
+// WithDeterministicThrottle admits throttled requests by credit rather
+// than at random: each request accrues the fraction of requests that may
+// pass, and is admitted once a whole request's worth has accrued. Exactly
+// the target fraction is admitted, evenly spaced, so at low request rates
+// recovery probes neither bunch together nor stall. It applies to the
+// estimation and probability functions and to ramp-up.
+func WithDeterministicThrottle(v bool) Option {
+	return func(b *Breaker) {
+		b.evenThrottle = v
+	}
+}
+
+// WithParent sets a parent breaker. Every success and failure recorded
+// by this breaker is also recorded by the parent (and its ancestors),
+// in its error count, ramp-up, stats and metrics, and while the parent
+// rejects requests, so does this breaker.
+// This is useful for grouping per-endpoint breakers under a per-host breaker.
+func WithParent(p *Breaker) Option {
+	return func(b *Breaker) {
+		b.parent = p
+	}
+}
+
+// WithStrict enables strict option validation. In strict mode, NewBreaker
+// and Reconfigure return an error instead of silently raising a backoff or
+// window below the minimum.
+func WithStrict(v bool) Option {
+	return func(b *Breaker) {
+		b.strict = v
+	}
+}
+
+// WithStateStore shares this breaker's state with other replicas through
+// the given StateStore. The breaker publishes its state and error count,
+// and rejects requests while the merged state of all replicas is open.
+// A request starts an exchange with the store at most once per sync
+// interval; the exchange runs on its own goroutine for at most the sync
+// timeout, so requests never wait for it. Store errors fall back to
+// local state only.
+func WithStateStore(s StateStore) Option {
+	return func(b *Breaker) {
+		b.store = s
+	}
+}
+
+// WithPeerID sets the identity of this replica in the StateStore.
+// By default, the hostname and process ID are used.
+func WithPeerID(id string) Option {
+	return func(b *Breaker) {
+		b.peerID = id
+	}
+}
+
+// WithMergePolicy sets the policy used to combine replica states from
+// the StateStore. The default is AnyOpen.
+func WithMergePolicy(p MergePolicy) Option {
+	return func(b *Breaker) {
+		b.mergePolicy = p
+	}
+}
+
+// WithSyncInterval sets the minimum time between exchanges with the
+// StateStore. Replicas that have not published for three intervals are
+// ignored. The default is 1 second.
+func WithSyncInterval(d time.Duration) Option {
+	return func(b *Breaker) {
+		b.syncInterval = d
+	}
+}
+
+// WithSyncTimeout limits each exchange with the StateStore, which is
+// started by a request but is not bound by the request's context.
+// If the store does not respond in time, the breaker falls back to its
+// local state until the next exchange. The default is 100ms.
+func WithSyncTimeout(d time.Duration) Option {
+	return func(b *Breaker) {
+		b.syncTimeout = d
+	}
+}
+
+// WithHistorySize sets the number of recent transitions kept for History.
+// The default is 32.
+func WithHistorySize(n int) Option {
+	return func(b *Breaker) {
+		b.historySize = n
+	}
+}
+
+// WithFlapDetection marks the breaker as flapping when it makes more
+// than n automatic transitions within period. When it starts flapping,
+// the WithOnFlap callback is invoked, a FlapRecorder metrics collector
+// is notified, and snapshots report Flapping until the breaker has
+// stayed closed for the stable period (by default, the same period).
+// Transitions made by ForceOpen, ForceClose, Reset and Restore are not counted.
+func WithFlapDetection(n int, period time.Duration) Option {
+	return func(b *Breaker) {
+		b.flapTransitions = n
+		b.flapPeriod = period
+	}
+}
+
+// WithFlapDampening lengthens the lockout while the breaker is flapping:
+// each reopen doubles it, starting from the breaker's lockout (or one
+// second if there is none), up to maxLockout. The lockout returns
+// to normal once the breaker has stayed closed for the stable period;
+// if stable is zero, the flap detection period is used.
+// It has no effect unless WithFlapDetection is also used.
+func WithFlapDampening(maxLockout, stable time.Duration) Option {
+	return func(b *Breaker) {
+		b.dampenMax = maxLockout
+		b.flapStable = stable
+	}
+}
+
 // WithMetrics sets an optional MetricsCollector for the breaker.
 func WithMetrics(m MetricsCollector) Option {
 	return func(b *Breaker) {
@@ -82,6 +276,28 @@ func WithMetrics(m MetricsCollector) Option {
 	}
 }
 
+// WithLabels sets a function that extracts labels, such as the tenant or
+// route, from the context passed to Run or Allow. The labels are passed
+// to the metrics collector if it implements LabeledMetricsCollector, and
+// are extracted only then. Collectors that do not implement it receive
+// the same calls without labels.
+func WithLabels(fn LabelFunc) Option {
+	return func(b *Breaker) {
+		b.labelsFunc = fn
+	}
+}
+
+// WithStats enables rolling statistics over the given window: counts of
+// successes, failures, timeouts, rejections and excluded errors, and
+// latency percentiles, available from Stats and in snapshots taken with
+// IncludeStats. The window is divided into ten buckets, so counts roll off
+// a tenth of the window at a time. Zero, the default, disables them.
+func WithStats(window time.Duration) Option {
+	return func(b *Breaker) {
+		b.statsWindow = window
+	}
+}
+
 // WithOnStateChange sets a callback that is invoked on every state transition.
 // The callback runs synchronously after the state mutex is released, so it
 // does not block other requests from evaluating state. However, it is called
@@ -92,6 +308,24 @@ func WithOnStateChange(fn func(breakerName string, from, to State)) Option {
 	}
 }
 
+// WithOnConfigChange sets a callback that is invoked after the breaker
+// is reconfigured via Reconfigure. Like WithOnStateChange, it runs
+// synchronously after the state mutex is released.
+func WithOnConfigChange(fn func(breakerName string)) Option {
+	return func(b *Breaker) {
+		b.onConfigChange = fn
+	}
+}
+
+// WithOnFlap sets a callback invoked when the breaker starts flapping,
+// with the number of transitions made within the flap detection period.
+// Like the state change callback, it is invoked without holding any lock.
+func WithOnFlap(fn func(breakerName string, transitions int)) Option {
+	return func(b *Breaker) {
+		b.onFlap = fn
+	}
+}
+
 // WithIsSuccessful sets a callback to classify errors as successes.
 // When this returns true for an error, the call is counted as a success
 // and does not contribute to the error threshold (e.g., HTTP 404).
@@ -109,3 +343,141 @@ func WithIsExcluded(fn func(error) bool) Option {
 		b.isExcluded = fn
 	}
 }
+
+// estimationSamples are the ticks at which estimation functions are checked.
+var estimationSamples = []int{1, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+
+// allTicks are the ticks at which the inputs to curve builders are checked.
+var allTicks = func() []int {
+	ticks := make([]int, 100)
+	for i := range ticks {
+		ticks[i] = i + 1
+	}
+	return ticks
+}()
+
+// validate checks option values before defaults are applied.
+// All problems are reported, joined into a single error.
+func (b *Breaker) validate() error {
+	var errs []error
+	invalid := func(option, detail string, err error) {
+		errs = append(errs, OptionError{Option: option, Detail: detail, Err: err})
+	}
+
+	for _, d := range []struct {
+		option string
+		value  time.Duration
+	}{
+		{"WithTimeout", b.timeout},
+		{"WithBackOff", b.backoff},
+		{"WithWindow", b.window},
+		{"WithLockOut", b.lockout},
+		{"WithSyncInterval", b.syncInterval},
+		{"WithSyncTimeout", b.syncTimeout},
+		{"WithFlapDetection", b.flapPeriod},
+		{"WithFlapDampening", b.dampenMax},
+		{"WithFlapDampening", b.flapStable},
+		{"WithStats", b.statsWindow},
+	} {
+		if d.value < 0 {
+			invalid(d.option, d.value.String(), ErrNegativeDuration)
+		}
+	}
+
+	if p := b.lockoutPolicy; p != nil {
+		for _, d := range []time.Duration{p.Base, p.Max} {
+			if d < 0 {
+				invalid("WithLockoutPolicy", d.String(), ErrNegativeDuration)
+			}
+		}
+		if p.Multiplier != 0 && p.Multiplier < 1 {
+			invalid("WithLockoutPolicy", fmt.Sprintf("multiplier %g < 1", p.Multiplier), ErrOutOfRange)
+		}
+		if p.Jitter < 0 || p.Jitter > 1 {
+			invalid("WithLockoutPolicy", fmt.Sprintf("jitter %g not in [0, 1]", p.Jitter), ErrOutOfRange)
+		}
+	}
+
+	if b.rampStep > 100 {
+		invalid("WithRampUp", fmt.Sprintf("step %d > 100", b.rampStep), ErrOutOfRange)
+	}
+
+	if b.historySize < 0 {
+		invalid("WithHistorySize", fmt.Sprint(b.historySize), ErrNegativeSize)
+	}
+	if b.flapTransitions < 0 {
+		invalid("WithFlapDetection", fmt.Sprint(b.flapTransitions), ErrNegativeSize)
+	}
+	if b.flapTransitions > 0 && b.flapPeriod == 0 {
+		invalid("WithFlapDetection", "period must be positive", ErrBelowMinimum)
+	}
+
+	if b.threshold == math.MaxUint32 {
+		invalid("WithThreshold", fmt.Sprint(b.threshold), ErrThresholdOverflow)
+	}
+
+	if b.strict {
+		if b.backoff > 0 && b.backoff < minimumBackoff {
+			invalid("WithBackOff", fmt.Sprintf("%s < %s", b.backoff, minimumBackoff), ErrBelowMinimum)
+		}
+		if b.window > 0 && b.window < minimumWindow {
+			invalid("WithWindow", fmt.Sprintf("%s < %s", b.window, minimumWindow), ErrBelowMinimum)
+		}
+	}
+
+	window := b.window
+	switch {
+	case window == 0:
+		window = DefaultWindow
+	case window < minimumWindow:
+		window = minimumWindow
+	}
+	if b.lockout > window {
+		invalid("WithLockOut", fmt.Sprintf("%s > window %s", b.lockout, window), ErrLockoutExceedsWindow)
+	}
+
+	if b.estimate != nil {
+		if err := checkEstimationFunc(b.estimate); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if b.probability != nil {
+		if err := checkProbabilityFunc(b.probability); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkEstimationFunc calls f at a sample of ticks and
+// verifies that it neither panics nor exceeds 100.
+func checkEstimationFunc(f EstimationFunc) error {
+	return checkTicks("WithEstimationFunc", f, estimationSamples)
+}
+
+// checkTicks calls f at each of the given ticks and verifies that it
+// neither panics nor exceeds 100. Errors are reported against option.
+func checkTicks(option string, f EstimationFunc, ticks []int) (err error) {
+	tick := 0
+	defer func() {
+		if r := recover(); r != nil {
+			err = OptionError{
+				Option: option,
+				Detail: fmt.Sprintf("tick %d: %v", tick, r),
+				Err:    ErrEstimationPanic,
+			}
+		}
+	}()
+
+	for _, tick = range ticks {
+		if v := f(tick); v > 100 {
+			return OptionError{
+				Option: option,
+				Detail: fmt.Sprintf("tick %d returned %d", tick, v),
+				Err:    ErrEstimationRange,
+			}
+		}
+	}
+	return nil
+}
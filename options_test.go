@@ -0,0 +1,202 @@
+package circuit
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+		option  string
+	}{
+		{
+			name:    "negative timeout",
+			opts:    []Option{WithTimeout(-time.Second)},
+			wantErr: ErrNegativeDuration,
+			option:  "WithTimeout",
+		},
+		{
+			name:    "negative backoff",
+			opts:    []Option{WithBackOff(-time.Second)},
+			wantErr: ErrNegativeDuration,
+			option:  "WithBackOff",
+		},
+		{
+			name:    "negative window",
+			opts:    []Option{WithWindow(-time.Second)},
+			wantErr: ErrNegativeDuration,
+			option:  "WithWindow",
+		},
+		{
+			name:    "negative lockout",
+			opts:    []Option{WithLockOut(-time.Second)},
+			wantErr: ErrNegativeDuration,
+			option:  "WithLockOut",
+		},
+		{
+			name:    "negative history size",
+			opts:    []Option{WithHistorySize(-1)},
+			wantErr: ErrNegativeSize,
+			option:  "WithHistorySize",
+		},
+		{
+			name:    "negative flap transitions",
+			opts:    []Option{WithFlapDetection(-1, time.Minute)},
+			wantErr: ErrNegativeSize,
+			option:  "WithFlapDetection",
+		},
+		{
+			name:    "flap detection without period",
+			opts:    []Option{WithFlapDetection(3, 0)},
+			wantErr: ErrBelowMinimum,
+			option:  "WithFlapDetection",
+		},
+		{
+			name:    "negative dampening",
+			opts:    []Option{WithFlapDampening(-time.Second, 0)},
+			wantErr: ErrNegativeDuration,
+			option:  "WithFlapDampening",
+		},
+		{
+			name:    "negative lockout policy base",
+			opts:    []Option{WithLockoutPolicy(LockoutPolicy{Base: -time.Second})},
+			wantErr: ErrNegativeDuration,
+			option:  "WithLockoutPolicy",
+		},
+		{
+			name:    "lockout multiplier below one",
+			opts:    []Option{WithLockoutPolicy(LockoutPolicy{Multiplier: 0.5})},
+			wantErr: ErrOutOfRange,
+			option:  "WithLockoutPolicy",
+		},
+		{
+			name:    "lockout jitter out of range",
+			opts:    []Option{WithLockoutPolicy(LockoutPolicy{Jitter: 1.5})},
+			wantErr: ErrOutOfRange,
+			option:  "WithLockoutPolicy",
+		},
+		{
+			name:    "ramp-up step out of range",
+			opts:    []Option{WithRampUp(101)},
+			wantErr: ErrOutOfRange,
+			option:  "WithRampUp",
+		},
+		{
+			name:    "threshold overflow",
+			opts:    []Option{WithThreshold(math.MaxUint32)},
+			wantErr: ErrThresholdOverflow,
+			option:  "WithThreshold",
+		},
+		{
+			name:    "estimation out of range",
+			opts:    []Option{WithEstimationFunc(func(tick int) uint32 { return uint32(200 - tick) })},
+			wantErr: ErrEstimationRange,
+			option:  "WithEstimationFunc",
+		},
+		{
+			name: "estimation panics",
+			opts: []Option{WithEstimationFunc(func(tick int) uint32 {
+				return []uint32{100, 50}[tick-1]
+			})},
+			wantErr: ErrEstimationPanic,
+			option:  "WithEstimationFunc",
+		},
+		{
+			name:    "strict backoff below minimum",
+			opts:    []Option{WithStrict(true), WithBackOff(time.Millisecond)},
+			wantErr: ErrBelowMinimum,
+			option:  "WithBackOff",
+		},
+		{
+			name:    "strict window below minimum",
+			opts:    []Option{WithStrict(true), WithWindow(time.Millisecond)},
+			wantErr: ErrBelowMinimum,
+			option:  "WithWindow",
+		},
+		{
+			name:    "lockout exceeds window",
+			opts:    []Option{WithWindow(time.Second), WithLockOut(time.Minute)},
+			wantErr: ErrLockoutExceedsWindow,
+			option:  "WithLockOut",
+		},
+		{
+			name:    "lockout exceeds default window",
+			opts:    []Option{WithLockOut(time.Hour)},
+			wantErr: ErrLockoutExceedsWindow,
+			option:  "WithLockOut",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b, err := NewBreaker(tt.opts...)
+			if b != nil {
+				t.Fatal("expected nil breaker on error")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			var optErr OptionError
+			if !errors.As(err, &optErr) {
+				t.Fatal("expected OptionError type")
+			}
+			if optErr.Option != tt.option {
+				t.Fatalf("expected option %s, got %s", tt.option, optErr.Option)
+			}
+		})
+	}
+
+	t.Run("reports every problem", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewBreaker(WithTimeout(-1), WithWindow(-1))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "WithTimeout") || !strings.Contains(err.Error(), "WithWindow") {
+			t.Fatalf("expected both options in error, got %v", err)
+		}
+	})
+
+	t.Run("non-strict clamps", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithBackOff(time.Millisecond), WithWindow(time.Millisecond), WithLockOut(minimumWindow))
+		if b.backoff != minimumBackoff || b.window != minimumWindow {
+			t.Fatalf("expected clamped values, got backoff=%v window=%v", b.backoff, b.window)
+		}
+	})
+
+	t.Run("strict accepts valid options", func(t *testing.T) {
+		t.Parallel()
+		mustNewBreaker(t, WithStrict(true), WithBackOff(time.Second), WithWindow(time.Minute), WithLockOut(time.Second))
+	})
+
+	t.Run("built-in estimation funcs are valid", func(t *testing.T) {
+		t.Parallel()
+		for _, f := range []EstimationFunc{Linear, Logarithmic, Exponential, EaseInOut, JitteredLinear} {
+			if err := checkEstimationFunc(f); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	t.Run("reconfigure rejects invalid options", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithStrict(true), WithThreshold(3))
+		err := b.Reconfigure(WithThreshold(5), WithWindow(time.Millisecond))
+		if !errors.Is(err, ErrBelowMinimum) {
+			t.Fatalf("expected ErrBelowMinimum, got %v", err)
+		}
+		if b.threshold != 3 {
+			t.Fatalf("expected breaker to be unchanged, got threshold %d", b.threshold)
+		}
+	})
+}
@@ -0,0 +1,289 @@
+package circuit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// exportVersion is the current version of the ExportedState format.
+const exportVersion = 1
+
+// ErrorRecord is a number of errors recorded at one instant.
+type ErrorRecord struct {
+	At    time.Time `json:"at"`
+	Count uint32    `json:"count"`
+}
+
+// ExportedState is the serialisable state of a Breaker, produced by
+// Export and consumed by Restore. It includes the state, the timestamps
+// that drive lockout and backoff, and the errors in the current window.
+type ExportedState struct {
+	Version        int           `json:"version"`
+	Name           string        `json:"name"`
+	State          State         `json:"state"`
+	ClosedSince    *time.Time    `json:"closed_since,omitempty"`
+	OpenSince      *time.Time    `json:"open_since,omitempty"`
+	LockedSince    *time.Time    `json:"locked_since,omitempty"`
+	Lockout        Duration      `json:"lockout,omitempty"` // length of the lock that began at LockedSince
+	Reopens        uint          `json:"reopens,omitempty"` // consecutive reopens, for lockout escalation
+	ThrottledSince *time.Time    `json:"throttled_since,omitempty"`
+	Admission      uint32        `json:"admission,omitempty"` // admission while ramping up
+	Errors         []ErrorRecord `json:"errors,omitempty"`
+}
+
+// Export returns the breaker's current state in a form that can be
+// serialised and later passed to Restore, e.g. across a process restart.
+// This triggers lazy state evaluation.
+func (b *Breaker) Export() ExportedState {
+	b.stateMX.Lock()
+	from, to, transitioned := b.evaluateState()
+	es := ExportedState{
+		Version: exportVersion,
+		Name:    b.name,
+		State:   State(atomic.LoadUint32(&b.state)),
+		Reopens: b.reopens,
+	}
+	if es.State == Throttled {
+		es.Admission = b.admit
+	}
+	if atomic.LoadInt64(&b.lockedSince) != 0 {
+		es.Lockout = Duration(b.lockedDuration())
+	}
+	for _, ts := range []struct {
+		ns  int64
+		dst **time.Time
+	}{
+		{atomic.LoadInt64(&b.closedSince), &es.ClosedSince},
+		{atomic.LoadInt64(&b.openSince), &es.OpenSince},
+		{atomic.LoadInt64(&b.lockedSince), &es.LockedSince},
+		{atomic.LoadInt64(&b.throttledSince), &es.ThrottledSince},
+	} {
+		if ts.ns != 0 {
+			t := timeFromNS(ts.ns)
+			*ts.dst = &t
+		}
+	}
+	b.stateMX.Unlock()
+
+	b.notify(from, to, transitioned)
+
+	for at, count := range b.tracker.export() {
+		es.Errors = append(es.Errors, ErrorRecord{At: timeFromNS(at), Count: count})
+	}
+	sort.Slice(es.Errors, func(i, j int) bool {
+		return es.Errors[i].At.Before(es.Errors[j].At)
+	})
+
+	return es
+}
+
+// Restore replaces the breaker's state with a previously exported state.
+// Timestamps are restored as-is, so any remaining lockout or backoff time
+// is honoured, and errors that have since left the window are discarded.
+// If the restored state differs from the current one, the change is
+// reported like any other transition. Any pin set by ForceOpen or
+// ForceClose is removed. The exported state must belong to a breaker
+// with the same name, and an open or throttled state must carry the
+// time it began; otherwise nothing is changed.
+func (b *Breaker) Restore(es ExportedState) error {
+	internal, err := b.checkRestore(es)
+	if err != nil {
+		return err
+	}
+	b.restore(es, internal)
+	return nil
+}
+
+// checkRestore reports whether es can be restored into the breaker,
+// and returns its internal state.
+func (b *Breaker) checkRestore(es ExportedState) (uint32, error) {
+	if b.tracker == nil {
+		return 0, ErrNotInitialized
+	}
+	if es.Name != b.name {
+		return 0, fmt.Errorf("%w: breaker %q cannot restore state exported by %q", ErrStateMismatch, b.name, es.Name)
+	}
+	if es.Version != exportVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrStateMismatch, es.Version)
+	}
+
+	// without the time a state began, its lockout or backoff never ends
+	switch es.State {
+	case Closed:
+		return internalClosed, nil
+	case Throttled:
+		if es.ThrottledSince == nil {
+			return 0, fmt.Errorf("%w: breaker %q is throttled without throttled_since", ErrStateMismatch, b.name)
+		}
+		return internalThrottled, nil
+	case Open:
+		if es.OpenSince == nil {
+			return 0, fmt.Errorf("%w: breaker %q is open without open_since", ErrStateMismatch, b.name)
+		}
+		return internalOpen, nil
+	default:
+		return 0, ErrStateUnknown.withContext(b.name, es.State)
+	}
+}
+
+// restore applies a state accepted by checkRestore.
+func (b *Breaker) restore(es ExportedState, internal uint32) {
+	events := make(map[int64]uint32, len(es.Errors))
+	for _, rec := range es.Errors {
+		events[rec.At.UnixNano()] += rec.Count
+	}
+
+	b.stateMX.Lock()
+	atomic.StoreUint32(&b.forced, 0)
+	b.tracker.restore(events)
+	atomic.StoreInt64(&b.closedSince, nanosOrZero(es.ClosedSince))
+	atomic.StoreInt64(&b.openSince, nanosOrZero(es.OpenSince))
+	atomic.StoreInt64(&b.lockedSince, nanosOrZero(es.LockedSince))
+	b.reopens = es.Reopens
+	lockout := time.Duration(es.Lockout)
+	if lockout == 0 {
+		lockout = b.nextLockout()
+	}
+	atomic.StoreInt64(&b.lockedFor, int64(lockout))
+	atomic.StoreInt64(&b.throttledSince, nanosOrZero(es.ThrottledSince))
+	b.admit = b.rampStep
+	if es.Admission > 0 {
+		b.admit = min(es.Admission, 100)
+	}
+	from := atomic.SwapUint32(&b.state, internal)
+	atomic.StoreInt64(&b.lastSync, 0)
+	transitioned := from != internal
+	if transitioned {
+		t := Transition{From: State(from), To: es.State, At: b.now(), Errors: int(b.tracker.size()), Reason: reasonRestored}
+		b.emitStateChange(t, b.snapshot())
+	}
+	b.stateMX.Unlock()
+
+	if transitioned && b.onStateChange != nil {
+		b.onStateChange(b.name, State(from), es.State)
+	}
+}
+
+func nanosOrZero(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// SaveState writes the exported state of every breaker in the box
+// to w as a JSON array.
+func (bb *BreakerBox) SaveState(w io.Writer) error {
+	var states []ExportedState
+	for _, b := range bb.Breakers() {
+		states = append(states, b.Export())
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(states)
+}
+
+// RestoreState reads states written by SaveState and restores each one
+// into the box's breaker of the same name. States for breakers that are
+// not in the box are skipped, so breakers should be created first.
+// Every state is checked before any is restored, so if one cannot be
+// restored, no breaker is changed.
+func (bb *BreakerBox) RestoreState(r io.Reader) error {
+	var states []ExportedState
+	if err := json.NewDecoder(r).Decode(&states); err != nil {
+		return fmt.Errorf("circuit: unable to decode saved state: %w", err)
+	}
+
+	type pending struct {
+		b        *Breaker
+		es       ExportedState
+		internal uint32
+	}
+	var (
+		restorals []pending
+		errs      []error
+	)
+	for _, es := range states {
+		b := bb.Load(es.Name)
+		if b == nil {
+			continue
+		}
+		internal, err := b.checkRestore(es)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		restorals = append(restorals, pending{b, es, internal})
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, r := range restorals {
+		r.b.restore(r.es, r.internal)
+	}
+	return nil
+}
+
+// SaveStateFile writes the box's state to the named file via SaveState.
+// The state is synced to disk before the file is replaced atomically, so
+// a crash leaves either the old file or the new one, never a partial file.
+func (bb *BreakerBox) SaveStateFile(name string) error {
+	dir := filepath.Dir(name)
+	tmp, err := os.CreateTemp(dir, filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := bb.SaveState(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir makes a rename in dir durable. It is best-effort, since
+// some platforms cannot open or sync a directory.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
+
+// RestoreStateFile restores the box's state from the named file via
+// RestoreState. A missing file is not an error, since there is nothing
+// to restore on first start.
+func (bb *BreakerBox) RestoreStateFile(name string) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return bb.RestoreState(f)
+}
@@ -0,0 +1,237 @@
+package circuit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportRestore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("open breaker resumes with remaining lockout", func(t *testing.T) {
+		t.Parallel()
+		opts := []Option{WithName("persist"), WithThreshold(1), WithLockOut(time.Minute)}
+		old := mustNewBreaker(t, opts...)
+		old.tracker.incr()
+		old.tracker.incr()
+		if old.State() != Open {
+			t.Fatalf("expected Open, got %s", old.State())
+		}
+
+		data, err := json.Marshal(old.Export())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var es ExportedState
+		if err := json.Unmarshal(data, &es); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		restored := mustNewBreaker(t, opts...)
+		var transitions []string
+		restored.onStateChange = func(_ string, from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		}
+		if err := restored.Restore(es); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if restored.State() != Open {
+			t.Fatalf("expected restored breaker to be Open, got %s", restored.State())
+		}
+		if restored.Size() != 2 {
+			t.Fatalf("expected 2 restored errors, got %d", restored.Size())
+		}
+		snap, oldSnap := restored.Snapshot(), old.Snapshot()
+		if !snap.LockoutEnds.Equal(*oldSnap.LockoutEnds) {
+			t.Fatalf("expected lockout to end at %v, got %v", oldSnap.LockoutEnds, snap.LockoutEnds)
+		}
+		if len(transitions) != 1 || transitions[0] != "closed->open" {
+			t.Fatalf("expected closed->open transition, got %v", transitions)
+		}
+	})
+
+	t.Run("throttled breaker resumes backoff", func(t *testing.T) {
+		t.Parallel()
+		since := time.Now().Add(-30 * time.Second)
+		b := mustNewBreaker(t, WithName("throttled"), WithBackOff(time.Minute))
+		err := b.Restore(ExportedState{Version: exportVersion, Name: "throttled", State: Throttled, ThrottledSince: &since})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		snap := b.Snapshot()
+		if snap.State != Throttled {
+			t.Fatalf("expected Throttled, got %s", snap.State)
+		}
+		if want := since.Add(time.Minute); !snap.BackOffEnds.Equal(want) {
+			t.Fatalf("expected backoff to end at %v, got %v", want, snap.BackOffEnds)
+		}
+	})
+
+	t.Run("expired errors are discarded", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithName("expired"), WithWindow(time.Minute), WithThreshold(5))
+		err := b.Restore(ExportedState{
+			Version: exportVersion,
+			Name:    "expired",
+			State:   Closed,
+			Errors: []ErrorRecord{
+				{At: time.Now().Add(-time.Hour), Count: 3},
+				{At: time.Now(), Count: 2},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.Size() != 2 {
+			t.Fatalf("expected only recent errors, got %d", b.Size())
+		}
+	})
+
+	t.Run("restore clears a pin", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithName("persist"))
+		es := b.Export()
+		b.ForceOpen()
+		if err := b.Restore(es); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.Snapshot().Forced {
+			t.Fatal("expected pin to be removed")
+		}
+		if b.State() != Closed {
+			t.Fatalf("expected Closed, got %s", b.State())
+		}
+	})
+
+	t.Run("rejects mismatched state", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithName("a"))
+		if err := b.Restore(ExportedState{Version: exportVersion, Name: "b"}); !errors.Is(err, ErrStateMismatch) {
+			t.Fatalf("expected ErrStateMismatch for name, got %v", err)
+		}
+		if err := b.Restore(ExportedState{Version: 99, Name: "a"}); !errors.Is(err, ErrStateMismatch) {
+			t.Fatalf("expected ErrStateMismatch for version, got %v", err)
+		}
+		if err := b.Restore(ExportedState{Version: exportVersion, Name: "a", State: State(7)}); !errors.Is(err, ErrStateUnknown) {
+			t.Fatalf("expected ErrStateUnknown, got %v", err)
+		}
+		for _, state := range []State{Throttled, Open} {
+			if err := b.Restore(ExportedState{Version: exportVersion, Name: "a", State: state}); !errors.Is(err, ErrStateMismatch) {
+				t.Fatalf("expected ErrStateMismatch for %s without its start time, got %v", state, err)
+			}
+		}
+		if b.State() != Closed {
+			t.Fatalf("expected the breaker to be unchanged, got %s", b.State())
+		}
+		if err := (&Breaker{}).Restore(ExportedState{}); !errors.Is(err, ErrNotInitialized) {
+			t.Fatalf("expected ErrNotInitialized, got %v", err)
+		}
+	})
+}
+
+func TestBreakerBoxSaveRestore(t *testing.T) {
+	t.Parallel()
+
+	newBox := func() *BreakerBox {
+		bb := NewBreakerBox()
+		bb.Create(WithName("users"), WithLockOut(time.Minute))
+		bb.Create(WithName("orders"), WithThreshold(5))
+		return bb
+	}
+
+	t.Run("writer round trip", func(t *testing.T) {
+		t.Parallel()
+		old := newBox()
+		old.Load("users").tracker.incr()
+		old.Load("users").State()
+		old.Load("orders").tracker.incr()
+
+		var buf bytes.Buffer
+		if err := old.SaveState(&buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		restored := NewBreakerBox()
+		restored.Create(WithName("users"), WithLockOut(time.Minute))
+		restored.Create(WithName("orders"), WithThreshold(5))
+		if err := restored.RestoreState(&buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if restored.Load("users").State() != Open {
+			t.Fatalf("expected users to be Open, got %s", restored.Load("users").State())
+		}
+		if restored.Load("orders").Size() != 1 {
+			t.Fatalf("expected orders to have 1 error, got %d", restored.Load("orders").Size())
+		}
+	})
+
+	t.Run("unknown breakers are skipped", func(t *testing.T) {
+		t.Parallel()
+		old := newBox()
+		var buf bytes.Buffer
+		old.SaveState(&buf)
+
+		restored := NewBreakerBox()
+		restored.Create(WithName("users"))
+		if err := restored.RestoreState(&buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		t.Parallel()
+		if err := NewBreakerBox().RestoreState(bytes.NewBufferString("{")); err == nil {
+			t.Fatal("expected decode error")
+		}
+	})
+
+	t.Run("an invalid state restores nothing", func(t *testing.T) {
+		t.Parallel()
+		old := newBox()
+		old.Load("orders").tracker.incr()
+		states := []ExportedState{old.Load("orders").Export(), {Version: exportVersion, Name: "users", State: Throttled}}
+		data, _ := json.Marshal(states)
+
+		restored := newBox()
+		if err := restored.RestoreState(bytes.NewReader(data)); !errors.Is(err, ErrStateMismatch) {
+			t.Fatalf("expected ErrStateMismatch, got %v", err)
+		}
+		if restored.Load("orders").Size() != 0 {
+			t.Fatal("expected orders to be unchanged")
+		}
+	})
+
+	t.Run("file round trip", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "breakers.json")
+
+		restored := newBox()
+		if err := restored.RestoreStateFile(path); err != nil {
+			t.Fatalf("expected missing file to be ignored, got %v", err)
+		}
+
+		old := newBox()
+		old.Load("users").tracker.incr()
+		old.Load("users").State()
+		if err := old.SaveStateFile(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != 1 {
+			t.Fatalf("expected temporary files to be cleaned up, got %d entries", len(entries))
+		}
+
+		if err := restored.RestoreStateFile(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if restored.Load("users").State() != Open {
+			t.Fatalf("expected users to be Open, got %s", restored.Load("users").State())
+		}
+	})
+}
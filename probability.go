@@ -0,0 +1,123 @@
+package circuit
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// ProbabilityFunc is a higher-resolution alternative to EstimationFunc.
+// It takes the progress through the backoff period, in [0, 1], and returns
+// the probability, in [0, 1], that a request should be blocked.
+// Progress is calculated from the elapsed time without rounding it to a
+// tick, so long backoffs recover smoothly rather than in steps, and small
+// probabilities such as 0.005 can be expressed.
+type ProbabilityFunc func(progress float64) float64
+
+// LinearProbability blocks requests with a probability
+// that falls in proportion to the progress.
+func LinearProbability(progress float64) float64 {
+	return 1 - clampProgress(progress)
+}
+
+// LogarithmicProbability is Logarithmic, interpolated between ticks.
+func LogarithmicProbability(progress float64) float64 {
+	return interpolate(Logarithmic, progress)
+}
+
+// ExponentialProbability is Exponential, interpolated between ticks.
+func ExponentialProbability(progress float64) float64 {
+	return interpolate(Exponential, progress)
+}
+
+// EaseInOutProbability is EaseInOut, interpolated between ticks.
+func EaseInOutProbability(progress float64) float64 {
+	return interpolate(EaseInOut, progress)
+}
+
+// JitteredLinearProbability is like LinearProbability but adds
+// random jitter of +/- 0.05 to reduce thundering herd effects. The
+// jitter is drawn from the global source in math/rand/v2, even for a
+// breaker with WithRand; for reproducible jitter, use
+// JitterProbability(LinearProbability, 0.05, src).
+func JitteredLinearProbability(progress float64) float64 {
+	return clampProgress(LinearProbability(progress) + 0.1*rand.Float64() - 0.05)
+}
+
+// JitterProbability returns f with up to ±amount added to each result
+// at random, clamped to [0, 1]. JitteredLinearProbability is
+// JitterProbability(LinearProbability, 0.05, nil). As with Jitter, the
+// jitter is drawn from src, or from the global source in math/rand/v2
+// if src is nil, and the function is safe for concurrent use.
+func JitterProbability(f ProbabilityFunc, amount float64, src rand.Source) (ProbabilityFunc, error) {
+	if f == nil {
+		return nil, curveError("JitterProbability", "nil probability function", ErrOutOfRange)
+	}
+	if !(amount >= 0 && amount <= 1) {
+		return nil, curveError("JitterProbability", fmt.Sprintf("amount %g not in [0, 1]", amount), ErrOutOfRange)
+	}
+
+	r := newCurveRand(src)
+	return func(progress float64) float64 {
+		return clampProgress(f(progress) + 2*amount*r.Float64() - amount)
+	}, nil
+}
+
+// Interpolate converts an EstimationFunc, such as one made by CubicBezier,
+// into a ProbabilityFunc that interpolates linearly between its ticks.
+func Interpolate(f EstimationFunc) ProbabilityFunc {
+	return func(progress float64) float64 {
+		return interpolate(f, progress)
+	}
+}
+
+// interpolate evaluates f at a fractional tick. Tick n is at progress
+// n/100, and progress before tick 1 is treated as tick 1.
+func interpolate(f EstimationFunc, progress float64) float64 {
+	x := clampProgress(progress) * 100
+	if x <= 1 {
+		return float64(f(1)) / 100
+	}
+	lo := int(x)
+	if lo >= 100 {
+		return float64(f(100)) / 100
+	}
+	a, b := float64(f(lo)), float64(f(lo+1))
+	return (a + (b-a)*(x-float64(lo))) / 100
+}
+
+// clampProgress limits p to [0, 1], treating NaN as 0.
+func clampProgress(p float64) float64 {
+	if !(p > 0) {
+		return 0
+	}
+	return min(p, 1)
+}
+
+// probabilitySamples are the progress values at which probability functions are checked.
+var probabilitySamples = []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// checkProbabilityFunc calls f at a sample of progress values and
+// verifies that it neither panics nor leaves [0, 1].
+func checkProbabilityFunc(f ProbabilityFunc) (err error) {
+	progress := 0.0
+	defer func() {
+		if r := recover(); r != nil {
+			err = OptionError{
+				Option: "WithProbabilityFunc",
+				Detail: fmt.Sprintf("progress %g: %v", progress, r),
+				Err:    ErrEstimationPanic,
+			}
+		}
+	}()
+
+	for _, progress = range probabilitySamples {
+		if v := f(progress); !(v >= 0 && v <= 1) {
+			return OptionError{
+				Option: "WithProbabilityFunc",
+				Detail: fmt.Sprintf("progress %g returned %g", progress, v),
+				Err:    ErrProbabilityRange,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package circuit
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestProbabilityFuncs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("built-in curves", func(t *testing.T) {
+		t.Parallel()
+		for name, f := range map[string]ProbabilityFunc{
+			"linear":      LinearProbability,
+			"logarithmic": LogarithmicProbability,
+			"exponential": ExponentialProbability,
+			"ease-in-out": EaseInOutProbability,
+		} {
+			if v := f(0); v != 1 {
+				t.Fatalf("%s: expected to start at 1, got %g", name, v)
+			}
+			if v := f(1); v != 0 {
+				t.Fatalf("%s: expected to end at 0, got %g", name, v)
+			}
+			prev := f(0)
+			for i := 1; i <= 1000; i++ {
+				v := f(float64(i) / 1000)
+				if v > prev || v < 0 || v > 1 {
+					t.Fatalf("%s: expected a non-increasing curve within [0, 1] at %d, got %g after %g", name, i, v, prev)
+				}
+				prev = v
+			}
+		}
+	})
+
+	t.Run("linear is exact", func(t *testing.T) {
+		t.Parallel()
+		for _, p := range []float64{0, 0.005, 0.5, 0.995, 1} {
+			if v := LinearProbability(p); math.Abs(v-(1-p)) > 1e-12 {
+				t.Fatalf("LinearProbability(%g) = %g, want %g", p, v, 1-p)
+			}
+		}
+		if LinearProbability(-1) != 1 || LinearProbability(2) != 0 || LinearProbability(math.NaN()) != 1 {
+			t.Fatal("expected progress outside [0, 1] to be clamped")
+		}
+	})
+
+	t.Run("interpolates between ticks", func(t *testing.T) {
+		t.Parallel()
+		f := Interpolate(Logarithmic)
+		a, b := float64(Logarithmic(50))/100, float64(Logarithmic(51))/100
+		if v := f(0.505); math.Abs(v-(a+b)/2) > 1e-12 {
+			t.Fatalf("expected the midpoint of ticks 50 and 51, got %g", v)
+		}
+		if v := f(0.5); v != a {
+			t.Fatalf("expected tick 50 at progress 0.5, got %g", v)
+		}
+		if LogarithmicProbability(0.505) != f(0.505) {
+			t.Fatal("expected LogarithmicProbability to interpolate Logarithmic")
+		}
+	})
+
+	t.Run("jittered linear", func(t *testing.T) {
+		t.Parallel()
+		seen := map[float64]bool{}
+		for i := 0; i < 100; i++ {
+			v := JitteredLinearProbability(0.5)
+			if v < 0.45 || v > 0.55 {
+				t.Fatalf("expected 0.5±0.05, got %g", v)
+			}
+			seen[v] = true
+		}
+		if len(seen) < 2 {
+			t.Fatal("expected jitter to vary the result")
+		}
+		if v := JitteredLinearProbability(1); v < 0 || v > 0.05 {
+			t.Fatalf("expected a result clamped to [0, 0.05], got %g", v)
+		}
+	})
+
+	t.Run("JitterProbability", func(t *testing.T) {
+		t.Parallel()
+		f, err := JitterProbability(LinearProbability, 0.05, rand.NewPCG(5, 6))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r := rand.New(rand.NewPCG(5, 6))
+		for i := 0; i <= 100; i++ {
+			progress := float64(i) / 100
+			want := clampProgress(LinearProbability(progress) + 0.1*r.Float64() - 0.05)
+			if got := f(progress); got != want {
+				t.Fatalf("progress %g: expected %g from the source, got %g", progress, want, got)
+			}
+		}
+
+		for _, bad := range []float64{-0.1, 1.5, math.NaN()} {
+			if _, err := JitterProbability(LinearProbability, bad, nil); !errors.Is(err, ErrOutOfRange) {
+				t.Fatalf("amount %g: expected ErrOutOfRange, got %v", bad, err)
+			}
+		}
+		if _, err := JitterProbability(nil, 0.1, nil); !errors.Is(err, ErrOutOfRange) {
+			t.Fatalf("expected ErrOutOfRange for a nil function, got %v", err)
+		}
+	})
+}
+
+func TestWithProbabilityFunc(t *testing.T) {
+	t.Parallel()
+
+	throttled := func(t *testing.T, f ProbabilityFunc) *Breaker {
+		t.Helper()
+		b := mustNewBreaker(t, WithBackOff(time.Hour), WithProbabilityFunc(f))
+		cycle(b, Open)
+		cycle(b, Throttled)
+		return b
+	}
+
+	t.Run("blocks with the given probability", func(t *testing.T) {
+		t.Parallel()
+		b := throttled(t, func(float64) float64 { return 1 })
+		for i := 0; i < 50; i++ {
+			if !errors.Is(b.checkState(), ErrStateThrottled) {
+				t.Fatal("expected every request to be throttled")
+			}
+		}
+
+		b = throttled(t, func(float64) float64 { return 0 })
+		for i := 0; i < 50; i++ {
+			if err := b.checkState(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	t.Run("sees exact progress", func(t *testing.T) {
+		t.Parallel()
+		var seen []float64
+		b := throttled(t, func(p float64) float64 {
+			seen = append(seen, p)
+			return 0
+		})
+		seen = nil // drop the calls made by validation
+		b.checkState()
+		if len(seen) != 1 || seen[0] <= 0 || seen[0] >= 0.01 {
+			t.Fatalf("expected progress below the first tick, got %v", seen)
+		}
+	})
+
+	t.Run("replaces the estimation func", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithEstimationFunc(Exponential), WithProbabilityFunc(EaseInOutProbability))
+		if b.estimate != nil || b.probability == nil {
+			t.Fatal("expected the probability func to replace the estimation func")
+		}
+		b = mustNewBreaker(t, WithProbabilityFunc(EaseInOutProbability), WithEstimationFunc(Exponential))
+		if b.estimate == nil || b.probability != nil {
+			t.Fatal("expected the estimation func to replace the probability func")
+		}
+	})
+
+	t.Run("throttle curve", func(t *testing.T) {
+		t.Parallel()
+		curve := mustNewBreaker(t, WithProbabilityFunc(LinearProbability)).ThrottleCurve()
+		for i, v := range curve {
+			if v != Linear(i+1) {
+				t.Fatalf("tick %d: expected %d, got %d", i+1, Linear(i+1), v)
+			}
+		}
+	})
+
+	t.Run("reconfigure", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithEstimationFunc(Exponential))
+		if err := b.Reconfigure(WithProbabilityFunc(ExponentialProbability)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c := b.Config(); c.Estimation != "exponential" || !c.Smooth {
+			t.Fatalf("expected smooth exponential estimation, got %+v", c)
+		}
+		if err := b.Reconfigure(WithThreshold(3)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.probability == nil {
+			t.Fatal("expected the probability func to be kept")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for name, tc := range map[string]struct {
+			f       ProbabilityFunc
+			wantErr error
+		}{
+			"above one": {func(p float64) float64 { return p + 1 }, ErrProbabilityRange},
+			"negative":  {func(p float64) float64 { return -p }, ErrProbabilityRange},
+			"NaN":       {func(float64) float64 { return math.NaN() }, ErrProbabilityRange},
+			"panics":    {func(float64) float64 { panic("boom") }, ErrEstimationPanic},
+		} {
+			_, err := NewBreaker(WithProbabilityFunc(tc.f))
+			var optErr OptionError
+			if !errors.Is(err, tc.wantErr) || !errors.As(err, &optErr) || optErr.Option != "WithProbabilityFunc" {
+				t.Fatalf("%s: expected %v, got %v", name, tc.wantErr, err)
+			}
+		}
+	})
+}
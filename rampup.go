@@ -0,0 +1,33 @@
+package circuit
+
+import "sync/atomic"
+
+// rampUp adjusts the admission of a throttled breaker in ramp-up mode
+// after a call completes. A success raises it by the ramp-up step, and
+// once every request is admitted the breaker closes; a failure halves it.
+// Must be called without stateMX held.
+func (b *Breaker) rampUp(success bool) {
+	if atomic.LoadUint32(&b.rampStep) == 0 || atomic.LoadUint32(&b.state) != internalThrottled {
+		return
+	}
+
+	var transitioned bool
+	b.stateMX.Lock()
+	// the state or mode may have changed before the lock was acquired
+	if b.rampStep == 0 || atomic.LoadUint32(&b.state) != internalThrottled {
+		b.stateMX.Unlock()
+		return
+	}
+	if success {
+		b.admit = min(b.admit+b.rampStep, 100)
+	} else {
+		b.admit = max(b.admit/2, 1)
+	}
+	if b.admit == 100 {
+		b.detectFlap(internalClosed)
+		_, transitioned = b.changeStateTo(internalClosed, reasonRampedUp)
+	}
+	b.stateMX.Unlock()
+
+	b.notify(Throttled, Closed, transitioned)
+}
@@ -0,0 +1,176 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRampUp(t *testing.T) {
+	t.Parallel()
+
+	// throttle opens and then throttles b, whatever its threshold.
+	throttle := func(b *Breaker) {
+		for i := uint32(0); i <= b.threshold; i++ {
+			b.tracker.incr()
+		}
+		b.State()
+		cycle(b, Throttled)
+	}
+
+	admission := func(t *testing.T, b *Breaker) uint32 {
+		t.Helper()
+		snap := b.Snapshot()
+		if snap.State != Throttled {
+			t.Fatalf("expected a throttled breaker, got %+v", snap)
+		}
+		if snap.BackOffEnds != nil {
+			t.Fatal("expected no backoff in ramp-up mode")
+		}
+		return snap.Admission
+	}
+
+	t.Run("successes raise admission until closed", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(10), WithRampUp(25))
+		throttle(b)
+		if got := admission(t, b); got != 25 {
+			t.Fatalf("expected admission to start at the step, got %d", got)
+		}
+
+		for _, want := range []uint32{50, 75} {
+			b.recordOutcome(context.Background(), nil, 0)
+			if got := admission(t, b); got != want {
+				t.Fatalf("expected admission of %d, got %d", want, got)
+			}
+		}
+		b.recordOutcome(context.Background(), nil, 0)
+		if b.State() != Closed {
+			t.Fatal("expected breaker to close once fully admitted")
+		}
+		h := b.History()
+		if last := h[len(h)-1]; last.From != Throttled || last.Reason != "ramp-up complete" {
+			t.Fatalf("unexpected transition %+v", last)
+		}
+	})
+
+	t.Run("failures halve admission", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(10), WithRampUp(40))
+		throttle(b)
+		b.recordOutcome(context.Background(), nil, 0)
+		for _, want := range []uint32{40, 20, 10, 5, 2, 1, 1} {
+			b.recordOutcome(context.Background(), errors.New("boom"), 0)
+			if got := admission(t, b); got != want {
+				t.Fatalf("expected admission of %d, got %d", want, got)
+			}
+		}
+	})
+
+	t.Run("ignores the backoff", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithBackOff(10*time.Millisecond), WithRampUp(10))
+		throttle(b)
+		time.Sleep(15 * time.Millisecond)
+		if b.State() != Throttled {
+			t.Fatal("expected breaker to stay throttled after the backoff")
+		}
+	})
+
+	t.Run("reopens past the threshold", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithRampUp(10))
+		throttle(b)
+		b.recordOutcome(context.Background(), errors.New("boom"), 0)
+		if b.State() != Open {
+			t.Fatal("expected breaker to reopen")
+		}
+	})
+
+	t.Run("admits the permitted fraction", func(t *testing.T) {
+		t.Parallel()
+		for _, step := range []uint32{1, 100} {
+			b := mustNewBreaker(t, WithThreshold(10), WithRampUp(step))
+			throttle(b)
+			b.stateMX.Lock()
+			b.admit = step // keep the admission fixed while sampling
+			b.stateMX.Unlock()
+
+			var allowed int
+			for i := 0; i < 200; i++ {
+				if b.checkState() == nil {
+					allowed++
+				}
+			}
+			if step == 100 && allowed != 200 {
+				t.Fatalf("expected every request admitted, got %d", allowed)
+			}
+			if step == 1 && allowed > 20 {
+				t.Fatalf("expected few requests admitted, got %d", allowed)
+			}
+		}
+	})
+
+	t.Run("other states are unaffected", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithRampUp(50))
+		done, err := b.Allow(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		done(nil)
+		if s := b.Snapshot(); s.State != Closed || s.Admission != 0 {
+			t.Fatalf("expected a closed breaker without admission, got %+v", s)
+		}
+	})
+
+	t.Run("reconfigure", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithThreshold(10), WithBackOff(time.Hour))
+		throttle(b)
+		if err := b.Reconfigure(WithRampUp(60)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := admission(t, b); got != 60 {
+			t.Fatalf("expected ramp-up to start while throttled, got %d", got)
+		}
+		b.recordOutcome(context.Background(), nil, 0)
+		if b.State() != Closed {
+			t.Fatal("expected breaker to close")
+		}
+	})
+
+	t.Run("children ramp up their parent", func(t *testing.T) {
+		t.Parallel()
+		parent := mustNewBreaker(t, WithName("host"), WithThreshold(10), WithRampUp(25))
+		child := mustNewBreaker(t, WithName("host/a"), WithThreshold(10), WithParent(parent))
+		throttle(parent)
+
+		child.recordOutcome(context.Background(), errors.New("boom"), 0)
+		if got := admission(t, parent); got != 12 {
+			t.Fatalf("expected a child failure to halve the admission, got %d", got)
+		}
+		for i := 0; i < 4; i++ {
+			child.recordOutcome(context.Background(), nil, 0)
+		}
+		if parent.State() != Closed {
+			t.Fatalf("expected child successes to close the parent, got %+v", parent.Snapshot())
+		}
+	})
+
+	t.Run("export and restore", func(t *testing.T) {
+		t.Parallel()
+		src := mustNewBreaker(t, WithName("ramping"), WithThreshold(10), WithRampUp(30))
+		throttle(src)
+		src.recordOutcome(context.Background(), nil, 0)
+
+		b := mustNewBreaker(t, WithName("ramping"), WithThreshold(10), WithRampUp(30))
+		if err := b.Restore(src.Export()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := admission(t, b); got != 60 {
+			t.Fatalf("expected the restored admission, got %d", got)
+		}
+	})
+}
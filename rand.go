@@ -0,0 +1,47 @@
+package circuit
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// float64 returns a random number in [0, 1) from the breaker's source,
+// or the global source if it has none. Must be called with stateMX held.
+func (b *Breaker) float64() float64 {
+	if b.rng != nil {
+		return b.rng.Float64()
+	}
+	return rand.Float64()
+}
+
+// curveRand is the random source of a built curve, which may be called
+// concurrently. Without a source, it uses the global one in math/rand/v2.
+type curveRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newCurveRand(src rand.Source) *curveRand {
+	if src == nil {
+		return &curveRand{}
+	}
+	return &curveRand{r: rand.New(src)}
+}
+
+func (c *curveRand) IntN(n int) int {
+	if c.r == nil {
+		return rand.IntN(n)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.r.IntN(n)
+}
+
+func (c *curveRand) Float64() float64 {
+	if c.r == nil {
+		return rand.Float64()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.r.Float64()
+}
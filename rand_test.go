@@ -0,0 +1,104 @@
+package circuit
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRand(t *testing.T) {
+	t.Parallel()
+
+	// decisions records whether each of 200 throttled requests is allowed.
+	decisions := func(t *testing.T, opts ...Option) []bool {
+		t.Helper()
+		b := mustNewBreaker(t, append([]Option{WithBackOff(time.Hour)}, opts...)...)
+		cycle(b, Open)
+		cycle(b, Throttled)
+		// halfway through the backoff, where every curve is uncertain
+		atomic.StoreInt64(&b.throttledSince, time.Now().Add(-30*time.Minute).UnixNano())
+		got := make([]bool, 200)
+		for i := range got {
+			got[i] = b.checkState() == nil
+		}
+		return got
+	}
+
+	same := func(a, b []bool) bool {
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// each option is built from the seed, so that built curves get their own source
+	for name, opt := range map[string]func(seed uint64) Option{
+		"estimation": func(uint64) Option { return WithEstimationFunc(Linear) },
+		"built jitter": func(seed uint64) Option {
+			f, err := Jitter(Exponential, 20, rand.NewPCG(seed, seed))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return WithEstimationFunc(f)
+		},
+		"probability": func(uint64) Option { return WithProbabilityFunc(EaseInOutProbability) },
+		"built probability jitter": func(seed uint64) Option {
+			f, err := JitterProbability(LinearProbability, 0.2, rand.NewPCG(seed, seed))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return WithProbabilityFunc(f)
+		},
+		"ramp-up": func(uint64) Option { return WithRampUp(50) },
+	} {
+		t.Run(name+" replays", func(t *testing.T) {
+			t.Parallel()
+			a := decisions(t, opt(1), WithRand(rand.NewPCG(1, 2)))
+			b := decisions(t, opt(1), WithRand(rand.NewPCG(1, 2)))
+			if !same(a, b) {
+				t.Fatal("expected the same seed to replay the same decisions")
+			}
+			c := decisions(t, opt(3), WithRand(rand.NewPCG(3, 4)))
+			if same(a, c) {
+				t.Fatal("expected a different seed to make different decisions")
+			}
+		})
+	}
+
+	t.Run("lockout jitter replays", func(t *testing.T) {
+		t.Parallel()
+		lockouts := func(seed uint64) []time.Duration {
+			b := mustNewBreaker(t,
+				WithLockoutPolicy(LockoutPolicy{Base: time.Second, Multiplier: 1, Jitter: 0.5}),
+				WithRand(rand.NewPCG(seed, seed)),
+			)
+			var got []time.Duration
+			for i := 0; i < 10; i++ {
+				cycle(b, Open)
+				got = append(got, lockoutOf(t, b))
+				cycle(b, Throttled)
+			}
+			return got
+		}
+		a, b, c := lockouts(7), lockouts(7), lockouts(8)
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("expected the same seed to replay the lockouts, got %v and %v", a, b)
+			}
+		}
+		if a[0] == c[0] && a[1] == c[1] {
+			t.Fatalf("expected a different seed to vary the lockouts, got %v and %v", a, c)
+		}
+	})
+
+	t.Run("nil source uses the global one", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithRand(rand.NewPCG(1, 2)), WithRand(nil))
+		if b.rng != nil {
+			t.Fatal("expected no source")
+		}
+	})
+}
@@ -0,0 +1,252 @@
+// Package redisstore provides a circuit.StateStore backed by any server
+// that speaks the Redis protocol (RESP), such as Redis, Valkey or KeyDB.
+//
+// Each breaker is stored as a hash keyed by breaker name, with one field
+// per replica holding that replica's JSON-encoded circuit.PeerState.
+// The package has no dependencies beyond the standard library.
+package redisstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+const (
+	// DefaultKeyPrefix is prepended to breaker names to form hash keys.
+	DefaultKeyPrefix = "circuit:"
+	// DefaultTTL is how long a breaker's hash lives after its last publish.
+	DefaultTTL = time.Minute
+	// DefaultDialTimeout bounds connection attempts.
+	DefaultDialTimeout = 2 * time.Second
+	// DefaultTimeout bounds each command whose context has no earlier deadline.
+	DefaultTimeout = 2 * time.Second
+)
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithPassword authenticates each new connection with AUTH.
+func WithPassword(password string) Option {
+	return func(s *Store) {
+		s.password = password
+	}
+}
+
+// WithDB selects a database on each new connection with SELECT.
+func WithDB(db int) Option {
+	return func(s *Store) {
+		s.db = db
+	}
+}
+
+// WithKeyPrefix sets the prefix for hash keys. The default is "circuit:".
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// WithTTL sets how long a breaker's hash is kept after its last publish,
+// so that state from decommissioned breakers is eventually removed.
+// The default is 1 minute.
+func WithTTL(d time.Duration) Option {
+	return func(s *Store) {
+		s.ttl = d
+	}
+}
+
+// WithDialTimeout bounds connection attempts. The default is 2 seconds.
+func WithDialTimeout(d time.Duration) Option {
+	return func(s *Store) {
+		s.dialTimeout = d
+	}
+}
+
+// WithTimeout bounds each command, including connecting, unless its
+// context has an earlier deadline. The default is 2 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Store) {
+		s.timeout = d
+	}
+}
+
+// Store is a circuit.StateStore that talks to a Redis-protocol server.
+// It holds a single connection, which is re-established after any error.
+// Store is safe for concurrent use; commands are serialized.
+type Store struct {
+	addr        string
+	password    string
+	db          int
+	prefix      string
+	ttl         time.Duration
+	dialTimeout time.Duration
+	timeout     time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+var _ circuit.StateStore = (*Store)(nil)
+
+// New returns a Store for the server at addr (host:port).
+// No connection is made until the first command.
+func New(addr string, opts ...Option) *Store {
+	s := &Store{
+		addr:        addr,
+		prefix:      DefaultKeyPrefix,
+		ttl:         DefaultTTL,
+		dialTimeout: DefaultDialTimeout,
+		timeout:     DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Publish stores the replica's state in the breaker's hash and refreshes its TTL.
+func (s *Store) Publish(ctx context.Context, state circuit.PeerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	key := s.prefix + state.Breaker
+	if _, err := s.do(ctx, "HSET", key, state.Peer, string(data)); err != nil {
+		return err
+	}
+	if s.ttl > 0 {
+		secs := int64(s.ttl / time.Second)
+		if secs < 1 {
+			secs = 1
+		}
+		if _, err := s.do(ctx, "EXPIRE", key, strconv.FormatInt(secs, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Peers returns the state of every replica stored in the breaker's hash.
+func (s *Store) Peers(ctx context.Context, breaker string) ([]circuit.PeerState, error) {
+	reply, err := s.do(ctx, "HGETALL", s.prefix+breaker)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items)%2 != 0 {
+		return nil, fmt.Errorf("redisstore: unexpected HGETALL reply %v", reply)
+	}
+
+	peers := make([]circuit.PeerState, 0, len(items)/2)
+	for i := 1; i < len(items); i += 2 {
+		raw, _ := items[i].(string)
+		var p circuit.PeerState
+		if err := json.Unmarshal([]byte(raw), &p); err != nil {
+			return nil, fmt.Errorf("redisstore: invalid state for peer %v: %w", items[i-1], err)
+		}
+		peers = append(peers, p)
+	}
+	return peers, nil
+}
+
+// Close closes the underlying connection, if any.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeConn()
+}
+
+// do sends a command and reads its reply, connecting if needed.
+// The connection is dropped after any error, so the next command redials.
+func (s *Store) do(ctx context.Context, args ...string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := s.roundTrip(ctx, args...)
+	if err != nil {
+		var replyErr serverError
+		if !errors.As(err, &replyErr) {
+			_ = s.closeConn()
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// connect dials the server and performs AUTH and SELECT.
+// Caller must hold the lock.
+func (s *Store) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: s.dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("redisstore: unable to connect to %s: %w", s.addr, err)
+	}
+	s.conn = conn
+	s.rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	if s.password != "" {
+		if _, err := s.roundTrip(ctx, "AUTH", s.password); err != nil {
+			_ = s.closeConn()
+			return err
+		}
+	}
+	if s.db != 0 {
+		if _, err := s.roundTrip(ctx, "SELECT", strconv.Itoa(s.db)); err != nil {
+			_ = s.closeConn()
+			return err
+		}
+	}
+	return nil
+}
+
+// roundTrip writes a command and reads one reply on the open connection.
+// Caller must hold the lock.
+func (s *Store) roundTrip(ctx context.Context, args ...string) (any, error) {
+	// a zero deadline (no context deadline and no timeout) clears any previous one
+	deadline, _ := ctx.Deadline()
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if err := writeCommand(s.rw.Writer, args...); err != nil {
+		return nil, err
+	}
+	reply, err := readReply(s.rw.Reader)
+	if errors.Is(err, errNil) {
+		return nil, nil
+	}
+	return reply, err
+}
+
+// closeConn closes and forgets the connection. Caller must hold the lock.
+func (s *Store) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	s.rw = nil
+	return err
+}
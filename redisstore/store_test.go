@@ -0,0 +1,339 @@
+package redisstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+// fakeRedis is a minimal in-process server speaking enough of the
+// Redis protocol to exercise Store: AUTH, SELECT, HSET, HGETALL and EXPIRE.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	hashes   map[string]map[string]string
+	expiries map[string]string
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{
+		ln:       ln,
+		password: password,
+		hashes:   make(map[string]map[string]string),
+		expiries: make(map[string]string),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeRedis) addr() string {
+	return f.ln.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	authed := f.password == ""
+
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			authed = len(args) == 2 && args[1] == f.password
+			if authed {
+				w.WriteString("+OK\r\n")
+			} else {
+				w.WriteString("-WRONGPASS invalid password\r\n")
+			}
+		case !authed:
+			w.WriteString("-NOAUTH Authentication required.\r\n")
+		case cmd == "SELECT":
+			w.WriteString("+OK\r\n")
+		case cmd == "HSET" && len(args) == 4:
+			h, ok := f.hashes[args[1]]
+			if !ok {
+				h = make(map[string]string)
+				f.hashes[args[1]] = h
+			}
+			h[args[2]] = args[3]
+			w.WriteString(":1\r\n")
+		case cmd == "EXPIRE" && len(args) == 3:
+			f.expiries[args[1]] = args[2]
+			w.WriteString(":1\r\n")
+		case cmd == "HGETALL" && len(args) == 2:
+			h := f.hashes[args[1]]
+			w.WriteString("*" + strconv.Itoa(len(h)*2) + "\r\n")
+			for k, v := range h {
+				w.WriteString("$" + strconv.Itoa(len(k)) + "\r\n" + k + "\r\n")
+				w.WriteString("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+			}
+		default:
+			w.WriteString("-ERR unknown command '" + args[0] + "'\r\n")
+		}
+		f.mu.Unlock()
+		if w.Flush() != nil {
+			return
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("publish and read peers", func(t *testing.T) {
+		t.Parallel()
+		srv := newFakeRedis(t, "")
+		s := New(srv.addr(), WithTTL(30*time.Second))
+		defer s.Close()
+		ctx := context.Background()
+
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		if err := s.Publish(ctx, circuit.PeerState{Breaker: "api", Peer: "a", State: circuit.Open, Errors: 3, Updated: now}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Publish(ctx, circuit.PeerState{Breaker: "api", Peer: "b", State: circuit.Closed, Updated: now}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		peers, err := s.Peers(ctx, "api")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(peers) != 2 {
+			t.Fatalf("expected 2 peers, got %d", len(peers))
+		}
+		for _, p := range peers {
+			if p.Peer == "a" && (p.State != circuit.Open || p.Errors != 3 || !p.Updated.Equal(now)) {
+				t.Fatalf("unexpected state for peer a: %+v", p)
+			}
+		}
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		if _, ok := srv.hashes["circuit:api"]; !ok {
+			t.Fatal("expected hash under default key prefix")
+		}
+		if srv.expiries["circuit:api"] != "30" {
+			t.Fatalf("expected 30s TTL, got %q", srv.expiries["circuit:api"])
+		}
+	})
+
+	t.Run("missing breaker", func(t *testing.T) {
+		t.Parallel()
+		srv := newFakeRedis(t, "")
+		s := New(srv.addr())
+		defer s.Close()
+		peers, err := s.Peers(context.Background(), "nope")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(peers) != 0 {
+			t.Fatalf("expected no peers, got %d", len(peers))
+		}
+	})
+
+	t.Run("auth, select and prefix", func(t *testing.T) {
+		t.Parallel()
+		srv := newFakeRedis(t, "secret")
+		s := New(srv.addr(), WithPassword("secret"), WithDB(2), WithKeyPrefix("cb/"))
+		defer s.Close()
+		if err := s.Publish(context.Background(), circuit.PeerState{Breaker: "api", Peer: "a"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		if srv.commands[0] != "AUTH secret" || srv.commands[1] != "SELECT 2" {
+			t.Fatalf("unexpected handshake: %v", srv.commands[:2])
+		}
+		if _, ok := srv.hashes["cb/api"]; !ok {
+			t.Fatal("expected hash under custom key prefix")
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		t.Parallel()
+		srv := newFakeRedis(t, "secret")
+		s := New(srv.addr(), WithPassword("nope"))
+		defer s.Close()
+		err := s.Publish(context.Background(), circuit.PeerState{Breaker: "api", Peer: "a"})
+		if err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+			t.Fatalf("expected WRONGPASS error, got %v", err)
+		}
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		t.Parallel()
+		ln, _ := net.Listen("tcp", "127.0.0.1:0")
+		addr := ln.Addr().String()
+		ln.Close()
+		s := New(addr, WithDialTimeout(100*time.Millisecond))
+		if _, err := s.Peers(context.Background(), "api"); err == nil {
+			t.Fatal("expected connection error")
+		}
+	})
+
+	t.Run("unresponsive server times out", func(t *testing.T) {
+		t.Parallel()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen failed: %v", err)
+		}
+		defer ln.Close()
+		go func() {
+			// accept connections but never reply
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+
+		s := New(ln.Addr().String(), WithTimeout(50*time.Millisecond))
+		defer s.Close()
+		start := time.Now()
+		if _, err := s.Peers(context.Background(), "api"); err == nil {
+			t.Fatal("expected a timeout")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("expected the command to time out promptly, took %v", elapsed)
+		}
+	})
+
+	t.Run("reconnects after connection loss", func(t *testing.T) {
+		t.Parallel()
+		srv := newFakeRedis(t, "")
+		s := New(srv.addr())
+		defer s.Close()
+		ctx := context.Background()
+		if _, err := s.Peers(ctx, "api"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s.mu.Lock()
+		s.conn.Close()
+		s.mu.Unlock()
+
+		if _, err := s.Peers(ctx, "api"); err == nil {
+			t.Fatal("expected error on closed connection")
+		}
+		if _, err := s.Peers(ctx, "api"); err != nil {
+			t.Fatalf("expected reconnect, got %v", err)
+		}
+	})
+
+	t.Run("shares state between breakers", func(t *testing.T) {
+		t.Parallel()
+		srv := newFakeRedis(t, "")
+		storeA, storeB := New(srv.addr()), New(srv.addr())
+		defer storeA.Close()
+		defer storeB.Close()
+
+		opts := []circuit.Option{circuit.WithName("shared"), circuit.WithSyncInterval(10 * time.Millisecond), circuit.WithLockOut(time.Second)}
+		a, _ := circuit.NewBreaker(append(opts, circuit.WithStateStore(storeA), circuit.WithPeerID("a"))...)
+		b, _ := circuit.NewBreaker(append(opts, circuit.WithStateStore(storeB), circuit.WithPeerID("b"))...)
+
+		circuit.Run(a, context.Background(), func(ctx context.Context) (int, error) {
+			return 0, errors.New("boom")
+		})
+		a.Allow(context.Background()) // closed -> open
+
+		// exchanges run in the background, so poll until b sees a open
+		var err error
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			a.Allow(context.Background()) // publish open
+			if _, err = b.Allow(context.Background()); err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, circuit.ErrStateOpen) {
+			t.Fatalf("expected b to reject while a is open, got %v", err)
+		}
+	})
+}
+
+func TestReadReply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    any
+		wantErr bool
+	}{
+		{"simple string", "+OK\r\n", "OK", false},
+		{"integer", ":42\r\n", int64(42), false},
+		{"bulk string", "$5\r\nhello\r\n", "hello", false},
+		{"error", "-ERR nope\r\n", nil, true},
+		{"null bulk", "$-1\r\n", nil, true},
+		{"malformed", "+OK\n", nil, true},
+		{"unknown type", "?\r\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("array", func(t *testing.T) {
+		t.Parallel()
+		got, err := readReply(bufio.NewReader(strings.NewReader("*3\r\n$1\r\na\r\n:1\r\n$-1\r\n")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		items := got.([]any)
+		if len(items) != 3 || items[0] != "a" || items[1] != int64(1) || items[2] != nil {
+			t.Fatalf("unexpected array: %#v", items)
+		}
+	})
+}
@@ -23,23 +23,23 @@ func Run[T any](b *Breaker, ctx context.Context, fn func(context.Context) (T, er
 		return zero, err
 	}
 
-	if b.timeout > 0 {
+	if timeout := time.Duration(atomic.LoadInt64((*int64)(&b.timeout))); timeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 
-	start := time.Now()
+	start := b.now()
 
 	defer func() {
 		if r := recover(); r != nil {
-			b.recordOutcome(fmt.Errorf("panic: %v", r), time.Since(start))
+			b.recordOutcome(ctx, fmt.Errorf("panic: %v", r), b.since(start))
 			panic(r)
 		}
 	}()
 
 	result, err := fn(ctx)
-	b.recordOutcome(err, time.Since(start))
+	b.recordOutcome(ctx, err, b.since(start))
 
 	// convert context deadline errors to ErrTimeout for the caller
 	if err != nil && errors.Is(err, context.DeadlineExceeded) {
@@ -0,0 +1,204 @@
+package simulate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+// WriteCSV writes one row per sample, with a header row. The first column
+// is the time elapsed since the start of the scenario, in seconds.
+func (r *Result) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{
+		"elapsed", "state", "errors", "arrived", "admitted", "rejected", "succeeded", "failed", "timed_out",
+	})
+	for _, s := range r.Samples {
+		_ = cw.Write([]string{
+			strconv.FormatFloat(s.At.Sub(r.Start).Seconds(), 'f', -1, 64),
+			s.State.String(),
+			strconv.Itoa(s.Errors),
+			strconv.Itoa(s.Arrived),
+			strconv.Itoa(s.Admitted),
+			strconv.Itoa(s.Rejected),
+			strconv.Itoa(s.Succeeded),
+			strconv.Itoa(s.Failed),
+			strconv.Itoa(s.TimedOut),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the result as indented JSON.
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// plot dimensions, in pixels
+const (
+	plotWidth  = 960
+	plotHeight = 320
+	marginLeft = 56
+	marginTop  = 16
+	marginEnd  = 16
+	axisHeight = 40 // below the state band, for labels
+	bandHeight = 12
+	bandGap    = 4
+)
+
+// plot colours
+var (
+	colorBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colorAxis       = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	colorSucceeded  = color.RGBA{0x3b, 0x7d, 0xd8, 0xff}
+	colorFailed     = color.RGBA{0xd1, 0x49, 0x5b, 0xff}
+	colorRejected   = color.RGBA{0xc0, 0xc0, 0xc0, 0xff}
+	colorClosed     = color.RGBA{0x4c, 0x9f, 0x70, 0xff}
+	colorThrottled  = color.RGBA{0xed, 0xae, 0x49, 0xff}
+	colorOpen       = color.RGBA{0xd1, 0x49, 0x5b, 0xff}
+)
+
+func stateColor(s circuit.State) color.RGBA {
+	switch s {
+	case circuit.Throttled:
+		return colorThrottled
+	case circuit.Open:
+		return colorOpen
+	}
+	return colorClosed
+}
+
+// box is a filled rectangle in plot coordinates.
+type box struct {
+	x0, y0, x1, y1 float64
+	fill           color.RGBA
+}
+
+// boxes lays out the plot shared by WriteSVG and WritePNG: a stacked bar
+// per sample of failed, succeeded and rejected requests, above a band
+// coloured by the breaker's state (green closed, amber throttled, red open).
+func (r *Result) boxes() (boxes []box, peak int) {
+	peak = 1
+	for _, s := range r.Samples {
+		peak = max(peak, s.Arrived)
+	}
+
+	bottom := float64(plotHeight - axisHeight - bandHeight - bandGap)
+	width := float64(plotWidth - marginLeft - marginEnd)
+	scale := (bottom - marginTop) / float64(peak)
+	for i, s := range r.Samples {
+		x0 := marginLeft + width*float64(i)/float64(len(r.Samples))
+		x1 := marginLeft + width*float64(i+1)/float64(len(r.Samples))
+		y := bottom
+		for _, part := range []struct {
+			n    int
+			fill color.RGBA
+		}{
+			{s.Failed, colorFailed},
+			{s.Succeeded, colorSucceeded},
+			{s.Rejected, colorRejected},
+		} {
+			if part.n > 0 {
+				h := float64(part.n) * scale
+				boxes = append(boxes, box{x0, y - h, x1, y, part.fill})
+				y -= h
+			}
+		}
+		band := bottom + bandGap
+		boxes = append(boxes, box{x0, band, x1, band + bandHeight, stateColor(s.State)})
+	}
+
+	// axes
+	boxes = append(boxes,
+		box{marginLeft - 1, marginTop, marginLeft, bottom + 1, colorAxis},
+		box{marginLeft - 1, bottom, marginLeft + width, bottom + 1, colorAxis},
+	)
+	return boxes, peak
+}
+
+// WriteSVG writes a plot of the result as an SVG image: requests per step,
+// stacked as failed (red), succeeded (blue) and rejected (grey), above a
+// band showing the breaker's state (green closed, amber throttled, red open).
+func (r *Result) WriteSVG(w io.Writer) error {
+	boxes, peak := r.boxes()
+	ew := &errWriter{w: w}
+	ew.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		plotWidth, plotHeight, plotWidth, plotHeight)
+	ew.printf(`<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hex(colorBackground))
+	for _, b := range boxes {
+		ew.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`+"\n",
+			b.x0, b.y0, b.x1-b.x0, b.y1-b.y0, hex(b.fill))
+	}
+
+	bottom := plotHeight - axisHeight - bandHeight - bandGap
+	labelY := plotHeight - axisHeight + 14
+	var end time.Duration
+	if n := len(r.Samples); n > 0 {
+		end = r.Samples[n-1].At.Sub(r.Start)
+	}
+	ew.printf(`<text x="%d" y="%d" text-anchor="end">%d</text>`+"\n", marginLeft-6, marginTop+4, peak)
+	ew.printf(`<text x="%d" y="%d" text-anchor="end">0</text>`+"\n", marginLeft-6, bottom+4)
+	ew.printf(`<text x="%d" y="%d" text-anchor="end">state</text>`+"\n", marginLeft-6, bottom+bandGap+bandHeight-2)
+	ew.printf(`<text x="%d" y="%d">0s</text>`+"\n", marginLeft, labelY)
+	ew.printf(`<text x="%d" y="%d" text-anchor="end">%v</text>`+"\n", plotWidth-marginEnd, labelY, end)
+
+	x := marginLeft
+	for _, l := range []struct {
+		label string
+		fill  color.RGBA
+	}{
+		{"succeeded", colorSucceeded},
+		{"failed", colorFailed},
+		{"rejected", colorRejected},
+		{"closed", colorClosed},
+		{"throttled", colorThrottled},
+		{"open", colorOpen},
+	} {
+		ew.printf(`<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`+"\n", x, labelY+8, hex(l.fill))
+		ew.printf(`<text x="%d" y="%d">%s</text>`+"\n", x+14, labelY+17, l.label)
+		x += 90
+	}
+	ew.printf("</svg>\n")
+	return ew.err
+}
+
+// WritePNG writes the same plot as WriteSVG as a PNG image, without labels.
+func (r *Result) WritePNG(w io.Writer) error {
+	boxes, _ := r.boxes()
+	img := image.NewRGBA(image.Rect(0, 0, plotWidth, plotHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(colorBackground), image.Point{}, draw.Src)
+	for _, b := range boxes {
+		rect := image.Rect(int(b.x0+0.5), int(b.y0+0.5), int(b.x1+0.5), int(b.y1+0.5))
+		draw.Draw(img, rect, image.NewUniform(b.fill), image.Point{}, draw.Src)
+	}
+	return png.Encode(w, img)
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// errWriter remembers the first write error so that a sequence of writes
+// can be checked once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
@@ -0,0 +1,376 @@
+// Package simulate drives a circuit.Breaker through a scripted scenario
+// in virtual time, so that thresholds, backoffs and throttle curves can be
+// tuned before they are deployed.
+//
+// A Scenario describes the traffic: how many requests arrive per second,
+// how likely each is to fail and how long each takes, all as functions of
+// the time elapsed since the start. Run replays it against a breaker built
+// from the given options, with a virtual Clock and a seeded random source,
+// so the same scenario always produces the same Result. The Result holds a
+// timeline of admitted and rejected requests and breaker states, and can
+// be written as CSV, JSON, or an SVG or PNG plot.
+package simulate
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+// ErrFailure is the error reported to the breaker for requests the
+// scenario fails.
+var ErrFailure = errors.New("simulate: request failed")
+
+// defaultStart is the virtual time a scenario starts at unless it sets
+// Scenario.Start.
+var defaultStart = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is a virtual circuit.Clock that only moves when told to.
+// It is safe for concurrent use.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock set to start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// Scenario describes the traffic a breaker is driven through. The
+// functions are called with the time elapsed since the start of the
+// scenario; Rate is called once per Step, so it is treated as constant
+// within each step.
+type Scenario struct {
+	// Duration is how long the scenario runs. It is required.
+	Duration time.Duration
+	// Step is the interval between samples in the Result. Defaults to one second.
+	Step time.Duration
+	// Start is the virtual time the scenario starts at. Defaults to 2000-01-01 UTC.
+	Start time.Time
+	// Seed seeds the random arrivals, failures and latencies, and the
+	// breaker's random source. A scenario with the same seed replays exactly.
+	Seed uint64
+
+	// Rate is the mean number of requests arriving per second. Arrivals
+	// follow a Poisson process. It is required.
+	Rate func(elapsed time.Duration) float64
+	// FailureRate is the probability, from 0 to 1, that a request fails.
+	// Defaults to no failures.
+	FailureRate func(elapsed time.Duration) float64
+	// Latency returns how long a request takes, using r for any
+	// randomness. Defaults to no latency. A request that takes at least
+	// the breaker's timeout fails with context.DeadlineExceeded.
+	Latency func(r *rand.Rand, elapsed time.Duration) time.Duration
+}
+
+func (s Scenario) validate() error {
+	switch {
+	case s.Duration <= 0:
+		return fmt.Errorf("simulate: duration must be positive, got %v", s.Duration)
+	case s.Step < 0:
+		return fmt.Errorf("simulate: step must not be negative, got %v", s.Step)
+	case s.Rate == nil:
+		return errors.New("simulate: rate is required")
+	}
+	return nil
+}
+
+// Constant returns a rate or failure rate that is always v.
+func Constant(v float64) func(time.Duration) float64 {
+	return func(time.Duration) float64 { return v }
+}
+
+// Window returns a rate or failure rate that is inside from the elapsed
+// time from up to, but not including, to, and outside otherwise, e.g. to
+// script an outage.
+func Window(from, to time.Duration, inside, outside float64) func(time.Duration) float64 {
+	return func(elapsed time.Duration) float64 {
+		if elapsed >= from && elapsed < to {
+			return inside
+		}
+		return outside
+	}
+}
+
+// FixedLatency returns a latency that is always d.
+func FixedLatency(d time.Duration) func(*rand.Rand, time.Duration) time.Duration {
+	return func(*rand.Rand, time.Duration) time.Duration { return d }
+}
+
+// UniformLatency returns a latency distributed uniformly between lo and hi.
+func UniformLatency(lo, hi time.Duration) func(*rand.Rand, time.Duration) time.Duration {
+	return func(r *rand.Rand, _ time.Duration) time.Duration {
+		if hi <= lo {
+			return lo
+		}
+		return lo + time.Duration(r.Int64N(int64(hi-lo)))
+	}
+}
+
+// NormalLatency returns a normally distributed latency with the given mean
+// and standard deviation, truncated at zero.
+func NormalLatency(mean, stddev time.Duration) func(*rand.Rand, time.Duration) time.Duration {
+	return func(r *rand.Rand, _ time.Duration) time.Duration {
+		return max(0, mean+time.Duration(r.NormFloat64()*float64(stddev)))
+	}
+}
+
+// ExponentialLatency returns an exponentially distributed latency with
+// the given mean, which has the long tail typical of remote calls.
+func ExponentialLatency(mean time.Duration) func(*rand.Rand, time.Duration) time.Duration {
+	return func(r *rand.Rand, _ time.Duration) time.Duration {
+		return time.Duration(r.ExpFloat64() * float64(mean))
+	}
+}
+
+// Counts tallies requests. Outcomes are counted against the step the
+// request arrived in, so Admitted is always Succeeded plus Failed.
+type Counts struct {
+	Arrived   int `json:"arrived"`
+	Admitted  int `json:"admitted"`
+	Rejected  int `json:"rejected"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	TimedOut  int `json:"timed_out"` // failed by exceeding the breaker's timeout
+}
+
+func (c *Counts) add(o Counts) {
+	c.Arrived += o.Arrived
+	c.Admitted += o.Admitted
+	c.Rejected += o.Rejected
+	c.Succeeded += o.Succeeded
+	c.Failed += o.Failed
+	c.TimedOut += o.TimedOut
+}
+
+// Sample records one step of a scenario.
+type Sample struct {
+	// At is the virtual time at the end of the step.
+	At time.Time `json:"at"`
+	// State is the breaker's state at the end of the step.
+	State circuit.State `json:"state"`
+	// Errors is the number of errors in the breaker's window at the end of the step.
+	Errors int `json:"errors"`
+	Counts
+}
+
+// Result is the outcome of a scenario.
+type Result struct {
+	Start       time.Time            `json:"start"`
+	Step        circuit.Duration     `json:"step"`
+	Samples     []Sample             `json:"samples"`
+	Transitions []circuit.Transition `json:"transitions"`
+	Total       Counts               `json:"total"`
+}
+
+// completion is an admitted request waiting to report its outcome.
+type completion struct {
+	at   time.Duration
+	done func(error)
+	err  error
+}
+
+// completions is a min-heap of completions ordered by time.
+type completions []completion
+
+func (h completions) Len() int           { return len(h) }
+func (h completions) Less(i, j int) bool { return h[i].at < h[j].at }
+func (h completions) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *completions) Push(x any)        { *h = append(*h, x.(completion)) }
+func (h *completions) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// Run drives a breaker built with opts through s. The breaker is given a
+// virtual Clock and a random source seeded from s.Seed ahead of opts, so
+// options that set either take precedence and may make the run
+// non-deterministic.
+func Run(s Scenario, opts ...circuit.Option) (*Result, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	if s.Step == 0 {
+		s.Step = time.Second
+	}
+	if s.Start.IsZero() {
+		s.Start = defaultStart
+	}
+	if s.FailureRate == nil {
+		s.FailureRate = Constant(0)
+	}
+	if s.Latency == nil {
+		s.Latency = FixedLatency(0)
+	}
+
+	clock := NewClock(s.Start)
+	b, err := circuit.NewBreaker(append([]circuit.Option{
+		circuit.WithClock(clock),
+		circuit.WithRand(rand.NewPCG(s.Seed, ^s.Seed)),
+	}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	r := &run{
+		Scenario: s,
+		rng:      rand.New(rand.NewPCG(s.Seed, s.Seed)),
+		clock:    clock,
+		breaker:  b,
+		timeout:  time.Duration(b.Config().Timeout),
+	}
+	return r.run(), nil
+}
+
+// run holds the state of a scenario being replayed.
+type run struct {
+	Scenario
+	rng     *rand.Rand
+	clock   *Clock
+	breaker *circuit.Breaker
+	timeout time.Duration
+
+	pending completions
+	result  Result
+	lastAt  time.Time // time of the last transition collected
+	seenAt  int       // transitions collected at lastAt
+}
+
+func (r *run) run() *Result {
+	r.result = Result{Start: r.Start, Step: circuit.Duration(r.Step)}
+	arrival := r.nextArrival(0)
+	for end := r.Step; ; end += r.Step {
+		end = min(end, r.Duration)
+		var sample Sample
+		for {
+			if len(r.pending) > 0 && r.pending[0].at <= arrival && r.pending[0].at < end {
+				c := heap.Pop(&r.pending).(completion)
+				r.advance(c.at)
+				c.done(c.err)
+			} else if arrival < end {
+				r.advance(arrival)
+				r.arrive(arrival, &sample.Counts)
+				arrival = r.nextArrival(arrival)
+			} else {
+				break
+			}
+			r.collect()
+		}
+
+		r.advance(end)
+		sample.At = r.clock.Now()
+		sample.State = r.breaker.State()
+		sample.Errors = r.breaker.Size()
+		r.collect()
+		r.result.Samples = append(r.result.Samples, sample)
+		r.result.Total.add(sample.Counts)
+		if end == r.Duration {
+			return &r.result
+		}
+	}
+}
+
+// advance moves the virtual clock to elapsed.
+func (r *run) advance(elapsed time.Duration) {
+	r.clock.Set(r.Start.Add(elapsed))
+}
+
+// arrive offers a request arriving at elapsed to the breaker and, if it is
+// admitted, schedules its outcome.
+func (r *run) arrive(elapsed time.Duration, counts *Counts) {
+	counts.Arrived++
+	done, err := r.breaker.Allow(context.Background())
+	if err != nil {
+		counts.Rejected++
+		return
+	}
+	counts.Admitted++
+
+	var outcome error
+	if r.rng.Float64() < r.FailureRate(elapsed) {
+		outcome = ErrFailure
+	}
+	latency := max(0, r.Latency(r.rng, elapsed))
+	if r.timeout > 0 && latency >= r.timeout {
+		latency = r.timeout
+		outcome = context.DeadlineExceeded
+		counts.TimedOut++
+	}
+	if outcome != nil {
+		counts.Failed++
+	} else {
+		counts.Succeeded++
+	}
+	heap.Push(&r.pending, completion{at: elapsed + latency, done: done, err: outcome})
+}
+
+// nextArrival returns the time of the first arrival after from, or the
+// scenario's duration if there is none. Within each step the rate is
+// constant, so arrivals are exponentially spaced; a gap that crosses into
+// the next step is drawn again from there, which the memorylessness of the
+// exponential distribution makes exact.
+func (r *run) nextArrival(from time.Duration) time.Duration {
+	for from < r.Duration {
+		boundary := min((from/r.Step+1)*r.Step, r.Duration)
+		if rate := r.Rate(from); rate > 0 {
+			gap := r.rng.ExpFloat64() / rate * float64(time.Second)
+			if gap < float64(boundary-from) {
+				return from + max(time.Duration(math.Ceil(gap)), 1)
+			}
+		}
+		from = boundary
+	}
+	return r.Duration
+}
+
+// collect records the transitions made since it was last called: those
+// after the last one it saw, and any beyond it made at the same instant,
+// since the virtual clock can stand still across several of them.
+func (r *run) collect() {
+	seen := 0
+	for _, t := range r.breaker.History() {
+		switch {
+		case t.At.Before(r.lastAt):
+			continue
+		case t.At.Equal(r.lastAt):
+			if seen < r.seenAt {
+				seen++
+				continue
+			}
+			r.seenAt++
+		default:
+			r.lastAt, r.seenAt = t.At, 1
+		}
+		r.result.Transitions = append(r.result.Transitions, t)
+	}
+}
@@ -0,0 +1,330 @@
+package simulate
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"image/png"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schigh/circuit"
+)
+
+// outage fails every request between 20s and 40s of a two minute scenario.
+func outage(seed uint64) Scenario {
+	return Scenario{
+		Duration:    2 * time.Minute,
+		Seed:        seed,
+		Rate:        Constant(50),
+		FailureRate: Window(20*time.Second, 40*time.Second, 1, 0),
+		Latency:     UniformLatency(10*time.Millisecond, 50*time.Millisecond),
+	}
+}
+
+var outageOptions = []circuit.Option{
+	circuit.WithThreshold(20),
+	circuit.WithWindow(10 * time.Second),
+	circuit.WithBackOff(20 * time.Second),
+}
+
+func mustRun(t *testing.T, s Scenario, opts ...circuit.Option) *Result {
+	t.Helper()
+	res, err := Run(s, opts...)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return res
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("samples every step", func(t *testing.T) {
+		t.Parallel()
+		res := mustRun(t, Scenario{Duration: 10500 * time.Millisecond, Rate: Constant(100)})
+		if len(res.Samples) != 11 {
+			t.Fatalf("expected 11 samples, got %d", len(res.Samples))
+		}
+		if got := res.Samples[0].At.Sub(res.Start); got != time.Second {
+			t.Fatalf("expected the first sample after a second, got %v", got)
+		}
+		if got := res.Samples[10].At.Sub(res.Start); got != 10500*time.Millisecond {
+			t.Fatalf("expected the last sample at the end, got %v", got)
+		}
+		if res.Total.Arrived < 900 || res.Total.Arrived > 1200 {
+			t.Fatalf("expected about 1050 arrivals, got %d", res.Total.Arrived)
+		}
+		if res.Total.Admitted != res.Total.Arrived || res.Total.Succeeded != res.Total.Arrived {
+			t.Fatalf("expected every request to succeed, got %+v", res.Total)
+		}
+	})
+
+	t.Run("replays", func(t *testing.T) {
+		t.Parallel()
+		encode := func(res *Result) string {
+			var buf bytes.Buffer
+			if err := res.WriteJSON(&buf); err != nil {
+				t.Fatalf("WriteJSON failed: %v", err)
+			}
+			return buf.String()
+		}
+		a := encode(mustRun(t, outage(1), outageOptions...))
+		b := encode(mustRun(t, outage(1), outageOptions...))
+		if a != b {
+			t.Fatal("expected the same seed to replay the same result")
+		}
+		if c := encode(mustRun(t, outage(2), outageOptions...)); a == c {
+			t.Fatal("expected a different seed to produce a different result")
+		}
+	})
+
+	t.Run("outage", func(t *testing.T) {
+		t.Parallel()
+		res := mustRun(t, outage(3), outageOptions...)
+
+		for i, s := range res.Samples {
+			if s.Arrived != s.Admitted+s.Rejected || s.Admitted != s.Succeeded+s.Failed {
+				t.Fatalf("sample %d: inconsistent counts %+v", i, s.Counts)
+			}
+		}
+		if s := res.Samples[0]; s.State != circuit.Closed || s.Rejected != 0 || s.Failed != 0 {
+			t.Fatalf("expected a healthy start, got %+v", s)
+		}
+		if s := res.Samples[25]; s.State != circuit.Open || s.Admitted != 0 {
+			t.Fatalf("expected the breaker open during the outage, got %+v", s)
+		}
+		if s := res.Samples[len(res.Samples)-1]; s.State != circuit.Closed || s.Rejected != 0 {
+			t.Fatalf("expected the breaker to recover, got %+v", s)
+		}
+		if res.Total.Rejected == 0 || res.Total.Failed == 0 {
+			t.Fatalf("expected rejections and failures, got %+v", res.Total)
+		}
+
+		var path []circuit.State
+		for _, tr := range res.Transitions {
+			if tr.At.Before(res.Start) || tr.At.After(res.Samples[len(res.Samples)-1].At) {
+				t.Fatalf("transition outside the scenario: %+v", tr)
+			}
+			path = append(path, tr.To)
+		}
+		if len(path) < 3 || path[0] != circuit.Open || path[len(path)-1] != circuit.Closed {
+			t.Fatalf("expected the breaker to open and close again, got %v", path)
+		}
+	})
+
+	t.Run("records every transition", func(t *testing.T) {
+		t.Parallel()
+		res := mustRun(t, Scenario{
+			Duration: 10 * time.Minute,
+			Rate:     Constant(50),
+			FailureRate: func(elapsed time.Duration) float64 {
+				return float64(elapsed / (20 * time.Second) % 2)
+			},
+		}, outageOptions...)
+		if len(res.Transitions) <= 16 {
+			t.Fatalf("expected repeated outages to make many transitions, got %d", len(res.Transitions))
+		}
+		for i := 1; i < len(res.Transitions); i++ {
+			if prev, tr := res.Transitions[i-1], res.Transitions[i]; tr.From != prev.To || tr.At.Before(prev.At) {
+				t.Fatalf("transition %d does not follow %+v: %+v", i, prev, tr)
+			}
+		}
+	})
+
+	t.Run("timeouts", func(t *testing.T) {
+		t.Parallel()
+		res := mustRun(t, Scenario{
+			Duration: 5 * time.Second,
+			Rate:     Constant(2),
+			Latency:  FixedLatency(time.Second),
+		}, circuit.WithTimeout(200*time.Millisecond), circuit.WithThreshold(1000))
+		if res.Total.Admitted == 0 || res.Total.TimedOut != res.Total.Admitted || res.Total.Failed != res.Total.Admitted {
+			t.Fatalf("expected every request to time out, got %+v", res.Total)
+		}
+	})
+
+	t.Run("reports outcomes after the latency", func(t *testing.T) {
+		t.Parallel()
+		// every request fails, but only reports it after 10 seconds
+		res := mustRun(t, Scenario{
+			Duration:    20 * time.Second,
+			Rate:        Constant(10),
+			FailureRate: Constant(1),
+			Latency:     FixedLatency(10 * time.Second),
+		}, circuit.WithThreshold(5), circuit.WithTimeout(0))
+		if res.Samples[8].State != circuit.Closed || res.Samples[8].Errors != 0 {
+			t.Fatalf("expected no errors before the first outcome, got %+v", res.Samples[8])
+		}
+		if res.Samples[len(res.Samples)-1].State != circuit.Open {
+			t.Fatal("expected the breaker to open once outcomes arrive")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for name, s := range map[string]Scenario{
+			"no duration":   {Rate: Constant(1)},
+			"negative step": {Duration: time.Second, Step: -1, Rate: Constant(1)},
+			"no rate":       {Duration: time.Second},
+		} {
+			if _, err := Run(s); err == nil {
+				t.Fatalf("%s: expected an error", name)
+			}
+		}
+		if _, err := Run(Scenario{Duration: time.Second, Rate: Constant(1)}, circuit.WithHistorySize(-1)); err == nil {
+			t.Fatal("expected an invalid option to fail")
+		}
+	})
+}
+
+func TestLatency(t *testing.T) {
+	t.Parallel()
+
+	r := rand.New(rand.NewPCG(1, 1))
+	for name, tc := range map[string]struct {
+		f      func(*rand.Rand, time.Duration) time.Duration
+		lo, hi time.Duration
+	}{
+		"fixed":       {FixedLatency(time.Second), time.Second, time.Second},
+		"uniform":     {UniformLatency(time.Second, 2*time.Second), time.Second, 2 * time.Second},
+		"normal":      {NormalLatency(time.Second, time.Second), 0, time.Hour},
+		"exponential": {ExponentialLatency(time.Second), 0, time.Hour},
+	} {
+		var sum time.Duration
+		for i := 0; i < 1000; i++ {
+			d := tc.f(r, 0)
+			if d < tc.lo || d > tc.hi {
+				t.Fatalf("%s: latency %v out of range", name, d)
+			}
+			sum += d
+		}
+		if mean := sum / 1000; mean < tc.lo || mean > 2*time.Second {
+			t.Fatalf("%s: unexpected mean %v", name, mean)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+	c.Advance(time.Minute)
+	if !c.Now().Equal(start.Add(time.Minute)) {
+		t.Fatalf("expected the clock to advance, got %v", c.Now())
+	}
+	c.Set(start)
+	if !c.Now().Equal(start) {
+		t.Fatalf("expected the clock to be set, got %v", c.Now())
+	}
+
+	b, err := circuit.NewBreaker(circuit.WithClock(c), circuit.WithLockOut(time.Minute), circuit.WithWindow(time.Minute))
+	if err != nil {
+		t.Fatalf("NewBreaker failed: %v", err)
+	}
+	done, _ := b.Allow(context.Background())
+	done(errors.New("boom"))
+	if b.State() != circuit.Open {
+		t.Fatal("expected the breaker to open")
+	}
+	c.Advance(2 * time.Minute)
+	if b.State() != circuit.Throttled {
+		t.Fatal("expected the lockout to end in virtual time")
+	}
+}
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+
+	res := mustRun(t, outage(4), outageOptions...)
+
+	t.Run("csv", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		if err := res.WriteCSV(&buf); err != nil {
+			t.Fatalf("WriteCSV failed: %v", err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid CSV: %v", err)
+		}
+		if len(rows) != len(res.Samples)+1 || rows[0][0] != "elapsed" {
+			t.Fatalf("expected a header and a row per sample, got %d rows", len(rows))
+		}
+		if rows[25][0] != "25" || rows[25][1] != "open" {
+			t.Fatalf("unexpected row %v", rows[25])
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		if err := res.WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+		var got Result
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if got.Step != circuit.Duration(time.Second) || len(got.Samples) != len(res.Samples) ||
+			got.Samples[25] != res.Samples[25] || got.Total != res.Total {
+			t.Fatal("expected the result to round trip")
+		}
+	})
+
+	t.Run("svg", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		if err := res.WriteSVG(&buf); err != nil {
+			t.Fatalf("WriteSVG failed: %v", err)
+		}
+		svg := buf.String()
+		if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>\n") {
+			t.Fatal("expected an SVG document")
+		}
+		for _, c := range []string{hex(colorRejected), hex(colorThrottled), hex(colorOpen)} {
+			if !strings.Contains(svg, `fill="`+c+`"`) {
+				t.Fatalf("expected the plot to use %s", c)
+			}
+		}
+	})
+
+	t.Run("png", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		if err := res.WritePNG(&buf); err != nil {
+			t.Fatalf("WritePNG failed: %v", err)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("invalid PNG: %v", err)
+		}
+		if b := img.Bounds(); b.Dx() != plotWidth || b.Dy() != plotHeight {
+			t.Fatalf("unexpected size %v", b)
+		}
+		// the state band under the middle of the outage is red
+		x := marginLeft + (plotWidth-marginLeft-marginEnd)*25/len(res.Samples) + 2
+		y := plotHeight - axisHeight - bandHeight/2
+		if r, g, b, _ := img.At(x, y).RGBA(); uint8(r>>8) != colorOpen.R || uint8(g>>8) != colorOpen.G || uint8(b>>8) != colorOpen.B {
+			t.Fatalf("expected the open colour at (%d, %d), got %v", x, y, img.At(x, y))
+		}
+	})
+}
+
+func TestWriteError(t *testing.T) {
+	t.Parallel()
+
+	res := mustRun(t, Scenario{Duration: time.Second, Rate: Constant(1)})
+	if err := res.WriteSVG(failingWriter{}); err == nil {
+		t.Fatal("expected the write error")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
@@ -0,0 +1,186 @@
+package circuit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultSyncInterval is the default minimum time between
+	// exchanges with a StateStore.
+	DefaultSyncInterval = time.Second
+	// DefaultSyncTimeout is the default limit on each exchange with a StateStore.
+	DefaultSyncTimeout = 100 * time.Millisecond
+)
+
+// PeerState is the state of a breaker as published by one replica.
+type PeerState struct {
+	Breaker string    `json:"breaker"`
+	Peer    string    `json:"peer"`
+	State   State     `json:"state"`
+	Errors  uint32    `json:"errors"`
+	Updated time.Time `json:"updated"`
+}
+
+// StateStore shares breaker state between process replicas.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Publish records the state of a breaker on one replica,
+	// replacing anything previously published by that replica.
+	Publish(ctx context.Context, state PeerState) error
+
+	// Peers returns the latest state published by every replica for the named breaker.
+	Peers(ctx context.Context, breaker string) ([]PeerState, error)
+}
+
+// MergePolicy decides, from the fresh states published by all replicas
+// (including this one), whether a breaker should reject requests as if open.
+type MergePolicy func(peers []PeerState) bool
+
+// AnyOpen is a MergePolicy that treats the breaker as open
+// if any replica reports it as open.
+func AnyOpen(peers []PeerState) bool {
+	for _, p := range peers {
+		if p.State == Open {
+			return true
+		}
+	}
+	return false
+}
+
+// MajorityOpen is a MergePolicy that treats the breaker as open
+// if more than half of the replicas report it as open.
+func MajorityOpen(peers []PeerState) bool {
+	open := 0
+	for _, p := range peers {
+		if p.State == Open {
+			open++
+		}
+	}
+	return open*2 > len(peers)
+}
+
+// TotalErrorsExceed returns a MergePolicy that treats the breaker as open
+// if the sum of the error counts reported by all replicas exceeds n.
+func TotalErrorsExceed(n uint32) MergePolicy {
+	return func(peers []PeerState) bool {
+		var total uint64
+		for _, p := range peers {
+			total += uint64(p.Errors)
+		}
+		return total > uint64(n)
+	}
+}
+
+// defaultPeerID identifies this process when no peer ID is configured.
+func defaultPeerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// syncStore starts an exchange with the store if none has started within
+// the sync interval and none is in flight. The exchange runs on its own
+// goroutine, so requests never wait for the store; they use the verdict
+// of the last exchange to complete.
+func (b *Breaker) syncStore(ctx context.Context) {
+	now := b.now().UnixNano()
+	last := atomic.LoadInt64(&b.lastSync)
+	if now-last < int64(b.syncInterval) || !atomic.CompareAndSwapUint32(&b.syncing, 0, 1) {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&b.lastSync, last, now) {
+		atomic.StoreUint32(&b.syncing, 0)
+		return
+	}
+
+	// keep the request's values, such as trace IDs, but not its cancellation
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer atomic.StoreUint32(&b.syncing, 0)
+		b.exchange(ctx, now)
+	}()
+}
+
+// exchange publishes this replica's state and refreshes the merged verdict
+// from its peers, within the sync timeout. If the store cannot be read,
+// the verdict falls back to local state only.
+func (b *Breaker) exchange(ctx context.Context, now int64) {
+	ctx, cancel := context.WithTimeout(ctx, b.syncTimeout)
+	defer cancel()
+
+	_ = b.store.Publish(ctx, PeerState{
+		Breaker: b.name,
+		Peer:    b.peerID,
+		State:   State(atomic.LoadUint32(&b.state)),
+		Errors:  b.tracker.size(),
+		Updated: timeFromNS(now),
+	})
+
+	peers, err := b.store.Peers(ctx, b.name)
+	if err != nil {
+		atomic.StoreUint32(&b.peersOpen, 0)
+		return
+	}
+
+	// ignore replicas that have stopped publishing
+	staleBefore := timeFromNS(now).Add(-3 * b.syncInterval)
+	fresh := peers[:0]
+	for _, p := range peers {
+		if p.Updated.After(staleBefore) {
+			fresh = append(fresh, p)
+		}
+	}
+
+	var open uint32
+	if b.mergePolicy(fresh) {
+		open = 1
+	}
+	atomic.StoreUint32(&b.peersOpen, open)
+}
+
+// MemoryStateStore is an in-process StateStore. It is useful for tests,
+// and for sharing state between breakers in the same process.
+type MemoryStateStore struct {
+	mu     sync.RWMutex
+	states map[string]map[string]PeerState
+}
+
+// NewMemoryStateStore returns an empty MemoryStateStore.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{
+		states: make(map[string]map[string]PeerState),
+	}
+}
+
+// Publish records the state of a breaker on one replica.
+func (m *MemoryStateStore) Publish(_ context.Context, state PeerState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	peers, ok := m.states[state.Breaker]
+	if !ok {
+		peers = make(map[string]PeerState)
+		m.states[state.Breaker] = peers
+	}
+	peers[state.Peer] = state
+	return nil
+}
+
+// Peers returns the latest state published by every replica for the named breaker.
+func (m *MemoryStateStore) Peers(_ context.Context, breaker string) ([]PeerState, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	peers := make([]PeerState, 0, len(m.states[breaker]))
+	for _, p := range m.states[breaker] {
+		peers = append(peers, p)
+	}
+	return peers, nil
+}
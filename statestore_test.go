@@ -0,0 +1,254 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type failingStore struct{}
+
+func (failingStore) Publish(context.Context, PeerState) error { return errors.New("down") }
+
+func (failingStore) Peers(context.Context, string) ([]PeerState, error) {
+	return nil, errors.New("down")
+}
+
+// hungStore never responds; it returns only when its context is done.
+type hungStore struct{}
+
+func (hungStore) Publish(ctx context.Context, _ PeerState) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (hungStore) Peers(ctx context.Context, _ string) ([]PeerState, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// strictStore is a MemoryStateStore that refuses to publish with a done context.
+type strictStore struct {
+	*MemoryStateStore
+}
+
+func (s strictStore) Publish(ctx context.Context, state PeerState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStateStore.Publish(ctx, state)
+}
+
+// eventually polls cond until it is true or the timeout elapses.
+func eventually(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMergePolicies(t *testing.T) {
+	t.Parallel()
+
+	peers := []PeerState{
+		{Peer: "a", State: Open, Errors: 4},
+		{Peer: "b", State: Closed, Errors: 1},
+		{Peer: "c", State: Throttled, Errors: 0},
+	}
+
+	tests := []struct {
+		name   string
+		policy MergePolicy
+		peers  []PeerState
+		want   bool
+	}{
+		{"any open", AnyOpen, peers, true},
+		{"any open none", AnyOpen, peers[1:], false},
+		{"majority minority", MajorityOpen, peers, false},
+		{"majority", MajorityOpen, append([]PeerState{{State: Open}}, peers...)[:3], true},
+		{"majority empty", MajorityOpen, nil, false},
+		{"errors exceed", TotalErrorsExceed(4), peers, true},
+		{"errors at limit", TotalErrorsExceed(5), peers, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.policy(tt.peers); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryStateStore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := NewMemoryStateStore()
+	s.Publish(ctx, PeerState{Breaker: "x", Peer: "a", State: Closed})
+	s.Publish(ctx, PeerState{Breaker: "x", Peer: "a", State: Open})
+	s.Publish(ctx, PeerState{Breaker: "x", Peer: "b", State: Closed})
+	s.Publish(ctx, PeerState{Breaker: "y", Peer: "a", State: Closed})
+
+	peers, err := s.Peers(ctx, "x")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(peers))
+	}
+	for _, p := range peers {
+		if p.Peer == "a" && p.State != Open {
+			t.Fatal("expected latest state to replace earlier state")
+		}
+	}
+	if peers, _ := s.Peers(ctx, "missing"); len(peers) != 0 {
+		t.Fatalf("expected no peers, got %d", len(peers))
+	}
+}
+
+func TestBreakerStateStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("open replica rejects on others", func(t *testing.T) {
+		t.Parallel()
+		store := NewMemoryStateStore()
+		opts := []Option{WithName("shared"), WithStateStore(store), WithSyncInterval(10 * time.Millisecond), WithLockOut(time.Second)}
+		a := mustNewBreaker(t, append(opts, WithPeerID("a"))...)
+		b := mustNewBreaker(t, append(opts, WithPeerID("b"))...)
+
+		if _, err := b.Allow(context.Background()); err != nil {
+			t.Fatalf("expected b to allow, got %v", err)
+		}
+
+		a.tracker.incr()
+		if _, err := a.Allow(context.Background()); !errors.Is(err, ErrStateOpen) {
+			t.Fatalf("expected a to be open, got %v", err)
+		}
+		// the transition is published on a's next request
+		eventually(t, time.Second, func() bool {
+			a.Allow(context.Background())
+			peers, _ := store.Peers(context.Background(), "shared")
+			for _, p := range peers {
+				if p.Peer == "a" && p.State == Open {
+					return true
+				}
+			}
+			return false
+		})
+
+		var err error
+		eventually(t, time.Second, func() bool {
+			_, err = b.Allow(context.Background())
+			return err != nil
+		})
+		if !errors.Is(err, ErrStateOpen) {
+			t.Fatalf("expected b to reject while a is open, got %v", err)
+		}
+		var circErr Error
+		if !errors.As(err, &circErr) || circErr.BreakerName != "shared" {
+			t.Fatalf("expected rejection from 'shared', got %v", err)
+		}
+		if b.State() != Closed {
+			t.Fatalf("expected b's local state to stay Closed, got %s", b.State())
+		}
+	})
+
+	t.Run("majority policy", func(t *testing.T) {
+		t.Parallel()
+		store := NewMemoryStateStore()
+		store.Publish(context.Background(), PeerState{Breaker: "maj", Peer: "x", State: Open, Updated: time.Now().Add(time.Hour)})
+		b := mustNewBreaker(t, WithName("maj"), WithStateStore(store), WithPeerID("b"), WithMergePolicy(MajorityOpen))
+		b.exchange(context.Background(), time.Now().UnixNano())
+		if _, err := b.Allow(context.Background()); err != nil {
+			t.Fatalf("expected 1 of 2 open to allow, got %v", err)
+		}
+	})
+
+	t.Run("stale peers are ignored", func(t *testing.T) {
+		t.Parallel()
+		store := NewMemoryStateStore()
+		store.Publish(context.Background(), PeerState{Breaker: "stale", Peer: "x", State: Open, Updated: time.Now().Add(-time.Minute)})
+		b := mustNewBreaker(t, WithName("stale"), WithStateStore(store), WithPeerID("b"))
+		b.exchange(context.Background(), time.Now().UnixNano())
+		if _, err := b.Allow(context.Background()); err != nil {
+			t.Fatalf("expected stale open peer to be ignored, got %v", err)
+		}
+	})
+
+	t.Run("publishes error count", func(t *testing.T) {
+		t.Parallel()
+		store := NewMemoryStateStore()
+		b := mustNewBreaker(t, WithName("count"), WithStateStore(store), WithPeerID("b"), WithThreshold(5))
+		b.tracker.incr()
+		b.tracker.incr()
+		b.Allow(context.Background())
+		var peers []PeerState
+		eventually(t, time.Second, func() bool {
+			peers, _ = store.Peers(context.Background(), "count")
+			return len(peers) > 0
+		})
+		if len(peers) != 1 || peers[0].Errors != 2 || peers[0].Peer != "b" {
+			t.Fatalf("unexpected published state: %+v", peers)
+		}
+	})
+
+	t.Run("store errors fall back to local state", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithStateStore(failingStore{}))
+		atomic.StoreUint32(&b.peersOpen, 1)
+		b.exchange(context.Background(), time.Now().UnixNano())
+		if _, err := b.Allow(context.Background()); err != nil {
+			t.Fatalf("expected allow, got %v", err)
+		}
+	})
+
+	t.Run("a hung store does not stall requests", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithStateStore(hungStore{}), WithSyncTimeout(20*time.Millisecond), WithSyncInterval(time.Millisecond))
+		// requests do not wait for the exchange, even with no deadline of their own
+		for range 10 {
+			start := time.Now()
+			_, err := Run(b, context.Background(), func(context.Context) (int, error) { return 0, nil })
+			if err != nil {
+				t.Fatalf("expected the request to succeed, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+				t.Fatalf("expected the request not to wait for the store, took %v", elapsed)
+			}
+		}
+		// the exchange in flight ends at the sync timeout
+		eventually(t, time.Second, func() bool {
+			return atomic.LoadUint32(&b.syncing) == 0
+		})
+	})
+
+	t.Run("a cancelled request does not cancel the exchange", func(t *testing.T) {
+		t.Parallel()
+		store := strictStore{NewMemoryStateStore()}
+		b := mustNewBreaker(t, WithName("detached"), WithStateStore(store), WithPeerID("b"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		b.syncStore(ctx)
+		eventually(t, time.Second, func() bool {
+			peers, _ := store.Peers(context.Background(), "detached")
+			return len(peers) == 1
+		})
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithStateStore(NewMemoryStateStore()))
+		if b.peerID == "" || b.mergePolicy == nil || b.syncInterval != DefaultSyncInterval || b.syncTimeout != DefaultSyncTimeout {
+			t.Fatalf("expected defaults, got peerID=%q interval=%v timeout=%v", b.peerID, b.syncInterval, b.syncTimeout)
+		}
+		if _, err := NewBreaker(WithSyncTimeout(-time.Second)); !errors.Is(err, ErrNegativeDuration) {
+			t.Fatalf("expected a negative timeout to be rejected, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package circuit
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// statsBuckets is the number of buckets the stats window is divided into.
+// Counts roll over one bucket at a time.
+const statsBuckets = 10
+
+// The latency histogram has latencySubBins linear bins per power of two
+// nanoseconds, so percentiles are accurate to within about 6%. Latencies
+// beyond the last bin, about 73 minutes, are counted in it.
+const (
+	latencySubBits = 3
+	latencySubBins = 1 << latencySubBits
+	latencyBins    = 40 * latencySubBins
+)
+
+// Stats are a breaker's rolling statistics over the most recent window,
+// collected when enabled with WithStats.
+type Stats struct {
+	Window    Duration `json:"window"`
+	Successes uint64   `json:"successes"` // including errors classified as successful
+	Failures  uint64   `json:"failures"`
+	Timeouts  uint64   `json:"timeouts"` // also counted as failures
+	Rejected  uint64   `json:"rejected"`
+	Excluded  uint64   `json:"excluded"`
+
+	// RequestRate is the number of requests per second over the window,
+	// including rejected and excluded requests.
+	RequestRate float64 `json:"request_rate"`
+	// ErrorRate is the fraction of completed requests that failed.
+	ErrorRate float64 `json:"error_rate"`
+
+	// Latency percentiles of successes and failures.
+	Latency LatencyStats `json:"latency"`
+}
+
+// LatencyStats are latency percentiles estimated from a histogram.
+// They are zero if no latencies were recorded.
+type LatencyStats struct {
+	P50 Duration `json:"p50"`
+	P90 Duration `json:"p90"`
+	P99 Duration `json:"p99"`
+}
+
+// statsBucket holds the counts for one interval of the window.
+type statsBucket struct {
+	epoch     int64 // index of the interval held, since the Unix epoch
+	successes uint64
+	failures  uint64
+	timeouts  uint64
+	rejected  uint64
+	excluded  uint64
+	latency   [latencyBins]uint32
+}
+
+// rollingStats counts outcomes in a ring of buckets covering the window.
+type rollingStats struct {
+	mu      sync.Mutex
+	window  time.Duration
+	width   int64 // nanoseconds per bucket
+	buckets [statsBuckets]statsBucket
+}
+
+func newRollingStats(window time.Duration) *rollingStats {
+	return &rollingStats{
+		window: window,
+		width:  max(int64(window)/statsBuckets, 1),
+	}
+}
+
+// bucket returns the bucket for now, clearing it if it last held an
+// earlier interval. Must be called with mu held.
+func (s *rollingStats) bucket(now time.Time) *statsBucket {
+	epoch := now.UnixNano() / s.width
+	b := &s.buckets[epoch%statsBuckets]
+	if b.epoch != epoch {
+		*b = statsBucket{epoch: epoch}
+	}
+	return b
+}
+
+// outcome kinds counted by rollingStats.record
+const (
+	statSuccess = iota
+	statFailure
+	statTimeout
+	statRejected
+	statExcluded
+)
+
+// record counts an outcome at now. The latency of successes and failures
+// is added to the histogram.
+func (s *rollingStats) record(now time.Time, kind int, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.bucket(now)
+	switch kind {
+	case statSuccess:
+		b.successes++
+	case statFailure:
+		b.failures++
+	case statTimeout:
+		b.timeouts++
+		return
+	case statRejected:
+		b.rejected++
+		return
+	case statExcluded:
+		b.excluded++
+		return
+	}
+	b.latency[latencyBin(elapsed)]++
+}
+
+// stats sums the buckets within the window ending at now.
+func (s *rollingStats) stats(now time.Time) Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{Window: Duration(s.window)}
+	var hist [latencyBins]uint64
+	var total uint64
+	epoch := now.UnixNano() / s.width
+	for i := range s.buckets {
+		b := &s.buckets[i]
+		if b.epoch <= epoch-statsBuckets || b.epoch > epoch {
+			continue
+		}
+		st.Successes += b.successes
+		st.Failures += b.failures
+		st.Timeouts += b.timeouts
+		st.Rejected += b.rejected
+		st.Excluded += b.excluded
+		for j, n := range b.latency {
+			hist[j] += uint64(n)
+			total += uint64(n)
+		}
+	}
+
+	if completed := st.Successes + st.Failures; completed > 0 {
+		st.ErrorRate = float64(st.Failures) / float64(completed)
+	}
+	requests := st.Successes + st.Failures + st.Rejected + st.Excluded
+	st.RequestRate = float64(requests) / s.window.Seconds()
+	if total > 0 {
+		st.Latency = LatencyStats{
+			P50: Duration(percentile(&hist, total, 0.50)),
+			P90: Duration(percentile(&hist, total, 0.90)),
+			P99: Duration(percentile(&hist, total, 0.99)),
+		}
+	}
+	return st
+}
+
+// latencyBin returns the histogram bin for d. Durations below
+// latencySubBins nanoseconds have a bin each; above that, each power of
+// two is split into latencySubBins equal bins.
+func latencyBin(d time.Duration) int {
+	v := uint64(max(d, 0))
+	if v < latencySubBins {
+		return int(v)
+	}
+	shift := bits.Len64(v) - 1 - latencySubBits
+	bin := (shift+1)<<latencySubBits + int(v>>shift) - latencySubBins
+	return min(bin, latencyBins-1)
+}
+
+// latencyBinMid returns the midpoint of the durations counted in bin.
+func latencyBinMid(bin int) time.Duration {
+	if bin < latencySubBins {
+		return time.Duration(bin)
+	}
+	shift := bin>>latencySubBits - 1
+	lower := uint64(bin&(latencySubBins-1)+latencySubBins) << shift
+	return time.Duration(lower + (uint64(1)<<shift)/2)
+}
+
+// percentile returns the latency below which fraction p of the total
+// recorded latencies fall.
+func percentile(hist *[latencyBins]uint64, total uint64, p float64) time.Duration {
+	rank := uint64(p*float64(total) + 0.5)
+	rank = min(max(rank, 1), total)
+	var seen uint64
+	for bin, n := range hist {
+		seen += n
+		if seen >= rank {
+			return latencyBinMid(bin)
+		}
+	}
+	return latencyBinMid(latencyBins - 1)
+}
+
+// recordStat counts an outcome in the rolling stats, if enabled.
+func (b *Breaker) recordStat(kind int, elapsed time.Duration) {
+	if s := b.stats.Load(); s != nil {
+		s.record(b.now(), kind, elapsed)
+	}
+}
+
+// Stats returns the breaker's rolling statistics, or nil if they are not
+// enabled with WithStats.
+func (b *Breaker) Stats() *Stats {
+	s := b.stats.Load()
+	if s == nil {
+		return nil
+	}
+	st := s.stats(b.now())
+	return &st
+}
@@ -0,0 +1,244 @@
+package circuit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	// call reports err after elapsed on clock.
+	call := func(t *testing.T, b *Breaker, clock *fakeClock, elapsed time.Duration, err error) {
+		t.Helper()
+		done, rejected := b.Allow(context.Background())
+		if rejected != nil {
+			return
+		}
+		clock.advance(elapsed)
+		done(err)
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t)
+		if b.Stats() != nil || b.Snapshot(IncludeStats()).Stats != nil {
+			t.Fatal("expected no stats")
+		}
+	})
+
+	t.Run("counts outcomes", func(t *testing.T) {
+		t.Parallel()
+		excluded, ok := errors.New("excluded"), errors.New("ok")
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		b := mustNewBreaker(t,
+			WithClock(clock),
+			WithStats(time.Minute),
+			WithThreshold(3),
+			WithIsExcluded(func(err error) bool { return err == excluded }),
+			WithIsSuccessful(func(err error) bool { return err == ok }),
+		)
+		call(t, b, clock, time.Millisecond, nil)
+		call(t, b, clock, time.Millisecond, ok)
+		call(t, b, clock, time.Millisecond, excluded)
+		call(t, b, clock, time.Millisecond, errors.New("boom"))
+		call(t, b, clock, time.Millisecond, context.DeadlineExceeded)
+		call(t, b, clock, time.Millisecond, errors.New("boom"))
+		call(t, b, clock, time.Millisecond, errors.New("boom")) // opens
+		call(t, b, clock, time.Millisecond, nil)                // rejected
+		call(t, b, clock, time.Millisecond, nil)                // rejected
+
+		s := b.Stats()
+		want := Stats{
+			Window:      Duration(time.Minute),
+			Successes:   2,
+			Failures:    4,
+			Timeouts:    1,
+			Rejected:    2,
+			Excluded:    1,
+			RequestRate: 9.0 / 60,
+			ErrorRate:   4.0 / 6,
+		}
+		s.Latency = LatencyStats{}
+		if *s != want {
+			t.Fatalf("expected %+v, got %+v", want, *s)
+		}
+	})
+
+	t.Run("rolls over a bucket at a time", func(t *testing.T) {
+		t.Parallel()
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		b := mustNewBreaker(t, WithClock(clock), WithStats(10*time.Second))
+		for i := 0; i < 10; i++ {
+			if i > 0 {
+				clock.advance(time.Second)
+			}
+			call(t, b, clock, 0, nil)
+		}
+		if n := b.Stats().Successes; n != 10 {
+			t.Fatalf("expected 10 successes, got %d", n)
+		}
+		for want := uint64(9); ; want-- {
+			clock.advance(time.Second)
+			if n := b.Stats().Successes; n != want {
+				t.Fatalf("expected %d successes, got %d", want, n)
+			}
+			if want == 0 {
+				break
+			}
+		}
+
+		// a bucket reused after a long gap is cleared
+		call(t, b, clock, 0, nil)
+		clock.advance(time.Hour)
+		call(t, b, clock, 0, nil)
+		if n := b.Stats().Successes; n != 1 {
+			t.Fatalf("expected 1 success, got %d", n)
+		}
+	})
+
+	t.Run("latency percentiles", func(t *testing.T) {
+		t.Parallel()
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		b := mustNewBreaker(t, WithClock(clock), WithStats(time.Hour), WithThreshold(1000))
+		for i := 1; i <= 1000; i++ {
+			var err error
+			if i%2 == 0 {
+				err = errors.New("boom") // failures count too
+			}
+			call(t, b, clock, time.Duration(i)*time.Millisecond, err)
+		}
+		l := b.Stats().Latency
+		for _, p := range []struct {
+			got  Duration
+			want time.Duration
+		}{
+			{l.P50, 500 * time.Millisecond},
+			{l.P90, 900 * time.Millisecond},
+			{l.P99, 990 * time.Millisecond},
+		} {
+			if off := math.Abs(float64(p.got)/float64(p.want) - 1); off > 0.07 {
+				t.Fatalf("expected about %v, got %v", p.want, time.Duration(p.got))
+			}
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithStats(time.Minute))
+		_, _ = Run(b, context.Background(), func(context.Context) (int, error) { return 0, nil })
+		data, err := json.Marshal(b.Snapshot(IncludeStats()))
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var got BreakerState
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if got.Stats == nil || got.Stats.Successes != 1 || got.Stats.Window != Duration(time.Minute) {
+			t.Fatalf("expected stats in the snapshot, got %s", data)
+		}
+		if b.Snapshot().Stats != nil {
+			t.Fatal("expected no stats unless requested")
+		}
+	})
+
+	t.Run("parents count their children's outcomes", func(t *testing.T) {
+		t.Parallel()
+		parent := mustNewBreaker(t, WithName("host"), WithStats(time.Minute), WithThreshold(10))
+		child := mustNewBreaker(t, WithName("host/a"), WithParent(parent), WithThreshold(10))
+		for _, err := range []error{nil, nil, nil, errors.New("boom")} {
+			done, _ := child.Allow(context.Background())
+			done(err)
+		}
+		if s := parent.Stats(); s.Successes != 3 || s.Failures != 1 || s.ErrorRate != 0.25 {
+			t.Fatalf("expected 3 successes and 1 failure, got %+v", s)
+		}
+	})
+
+	t.Run("reconfigure", func(t *testing.T) {
+		t.Parallel()
+		b := mustNewBreaker(t, WithName("s"))
+		if err := b.Reconfigure(WithStats(time.Minute)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, _ = Run(b, context.Background(), func(context.Context) (int, error) { return 0, nil })
+		if s := b.Stats(); s == nil || s.Successes != 1 {
+			t.Fatalf("expected stats once enabled, got %+v", s)
+		}
+
+		// an unchanged window keeps the counts, and a new one restarts them
+		if err := b.Reconfigure(WithThreshold(2)); err != nil || b.Stats().Successes != 1 {
+			t.Fatalf("expected the stats to be kept, got %+v (%v)", b.Stats(), err)
+		}
+		if err := b.Reconfigure(WithStats(time.Hour)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s := b.Stats(); s.Window != Duration(time.Hour) || s.Successes != 0 {
+			t.Fatalf("expected restarted stats, got %+v", s)
+		}
+
+		// a reloaded document without a window disables them
+		box := NewBreakerBox()
+		_ = box.AddBYO(b)
+		if err := box.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{{Name: "s", StatsWindow: Duration(time.Minute)}}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c := b.Config(); c.StatsWindow != Duration(time.Minute) || b.Stats() == nil {
+			t.Fatalf("expected the reloaded window, got %+v", c)
+		}
+		if err := box.ApplyConfig(BoxConfig{Breakers: []BreakerConfig{{Name: "s"}}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.Stats() != nil || b.Config().StatsWindow != 0 {
+			t.Fatal("expected the stats to be disabled")
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		t.Parallel()
+		if _, err := NewBreaker(WithStats(-time.Second)); !errors.Is(err, ErrNegativeDuration) {
+			t.Fatalf("expected a negative window to be rejected, got %v", err)
+		}
+		c := mustNewBreaker(t, WithStats(time.Minute)).Config()
+		if c.StatsWindow != Duration(time.Minute) {
+			t.Fatalf("expected the window in the config, got %v", c.StatsWindow)
+		}
+		opts, err := c.Options()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mustNewBreaker(t, opts...).Stats() == nil {
+			t.Fatal("expected the config to round trip")
+		}
+		if err := (BreakerConfig{Name: "x", StatsWindow: -1}).Validate(); err == nil {
+			t.Fatal("expected a negative window to be invalid")
+		}
+	})
+}
+
+func TestLatencyBins(t *testing.T) {
+	t.Parallel()
+
+	prev := -1
+	for d := time.Duration(0); d < 2*time.Hour; d = d*9/8 + 1 {
+		bin := latencyBin(d)
+		if bin < prev {
+			t.Fatalf("%v: bins must not decrease, got %d after %d", d, bin, prev)
+		}
+		prev = bin
+		if bin == latencyBins-1 {
+			continue // the last bin also holds everything beyond it
+		}
+		if mid := latencyBinMid(bin); math.Abs(float64(mid-d)) > float64(d)/16+1 {
+			t.Fatalf("%v: bin %d has midpoint %v", d, bin, mid)
+		}
+	}
+	if bin := latencyBin(-time.Second); bin != 0 {
+		t.Fatalf("expected negative latencies in the first bin, got %d", bin)
+	}
+}
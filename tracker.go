@@ -6,6 +6,7 @@ import (
 )
 
 type errTracker struct {
+	clock         Clock // nil for the system clock
 	mu            sync.Mutex
 	events        map[int64]uint32
 	window        int64
@@ -15,17 +16,26 @@ type errTracker struct {
 
 const minEvictInterval = int64(50 * time.Millisecond)
 
-func newErrTracker(dur time.Duration) *errTracker {
+func newErrTracker(dur time.Duration, clock Clock) *errTracker {
 	return &errTracker{
+		clock:  clock,
 		events: make(map[int64]uint32),
 		window: int64(dur),
 	}
 }
 
+// now returns the current Unix nano timestamp from the tracker's clock.
+func (e *errTracker) now() int64 {
+	if e.clock != nil {
+		return e.clock.Now().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
 // incr records an error instance.
 func (e *errTracker) incr() {
 	e.mu.Lock()
-	n := time.Now().UnixNano()
+	n := e.now()
 	e.events[n]++
 	e.sz++
 	e.mu.Unlock()
@@ -38,7 +48,7 @@ func (e *errTracker) size() uint32 {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	now := time.Now().UnixNano()
+	now := e.now()
 	if e.sz > 0 && now-e.lastEvictNano >= minEvictInterval {
 		e.evict(now)
 		e.lastEvictNano = now
@@ -68,6 +78,42 @@ func (e *errTracker) evict(now int64) {
 	}
 }
 
+// resize changes the length of the tracking window. Errors that
+// fall outside the new window are evicted on the next size call.
+func (e *errTracker) resize(dur time.Duration) {
+	e.mu.Lock()
+	e.window = int64(dur)
+	e.lastEvictNano = 0
+	e.mu.Unlock()
+}
+
+// export returns a copy of the tracked error counts keyed by Unix nano timestamp.
+func (e *errTracker) export() map[int64]uint32 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.evict(e.now())
+
+	events := make(map[int64]uint32, len(e.events))
+	for k, v := range e.events {
+		events[k] = v
+	}
+	return events
+}
+
+// restore replaces all tracked errors. Errors outside the window
+// are evicted on the next size call.
+func (e *errTracker) restore(events map[int64]uint32) {
+	e.mu.Lock()
+	e.events = make(map[int64]uint32, len(events))
+	e.sz = 0
+	for k, v := range events {
+		e.events[k] += v
+		e.sz += v
+	}
+	e.lastEvictNano = 0
+	e.mu.Unlock()
+}
+
 // reset clears all tracked errors.
 func (e *errTracker) reset(do bool) {
 	if !do {
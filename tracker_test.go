@@ -10,7 +10,7 @@ func newTracker(t *testing.T, dur time.Duration) *errTracker {
 	if dur == 0 {
 		dur = time.Minute
 	}
-	return newErrTracker(dur)
+	return newErrTracker(dur, nil)
 }
 
 func TestTracker(t *testing.T) {